@@ -0,0 +1,89 @@
+package projector
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// BatchSizer chooses the fetch limit for each batch and learns from how the batch went.
+// A Worker with a BatchSizer ignores BatchSize.
+type BatchSizer interface {
+	// Limit returns the fetch limit for the next batch.
+	Limit() int
+	// Observe reports the outcome of applying a batch fetched with limit:
+	// how many events it held, how long Apply took and the error Apply returned (if any).
+	Observe(fetched, limit int, took time.Duration, err error)
+}
+
+// AdaptiveBatchSize grows the fetch limit while batches come back full and Apply is fast
+// (catching up), and shrinks it when Apply exceeds TargetLatency or fails with a timeout
+// (long transactions in steady state). The limit always stays within [Min, Max].
+//
+// It is safe for concurrent use, and keeps its state across Worker restarts.
+type AdaptiveBatchSize struct {
+	Min           int           // default: 16
+	Max           int           // default: 4096
+	Initial       int           // default: 256 (clamped to [Min, Max])
+	TargetLatency time.Duration // default: 1s
+
+	mu      sync.Mutex
+	current int
+}
+
+// Limit implements BatchSizer.
+func (a *AdaptiveBatchSize) Limit() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.current == 0 {
+		a.current = a.clamp(a.Initial)
+	}
+	return a.current
+}
+
+// Observe implements BatchSizer.
+func (a *AdaptiveBatchSize) Observe(fetched, limit int, took time.Duration, err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	target := a.TargetLatency
+	if target <= 0 {
+		target = time.Second
+	}
+
+	switch {
+	case err != nil && isTimeout(err), took > target:
+		a.current = a.clamp(limit / 2)
+	case err == nil && fetched >= limit && took < target/2:
+		a.current = a.clamp(limit * 2)
+	}
+}
+
+// clamp bounds n to [Min, Max], treating zero as the default initial size
+func (a *AdaptiveBatchSize) clamp(n int) int {
+	lo, hi := a.Min, a.Max
+	if lo <= 0 {
+		lo = 16
+	}
+	if hi <= 0 {
+		hi = 4096
+	}
+	if hi < lo {
+		hi = lo
+	}
+	if n <= 0 {
+		n = 256
+	}
+	return max(lo, min(hi, n))
+}
+
+// isTimeout reports whether err is a deadline or a driver/network timeout
+func isTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var t interface{ Timeout() bool }
+	return errors.As(err, &t) && t.Timeout()
+}
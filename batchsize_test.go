@@ -0,0 +1,123 @@
+package projector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	es "github.com/shogotsuneto/go-simple-eventstore"
+)
+
+func TestAdaptiveBatchSizeGrowsWhenFullAndFast(t *testing.T) {
+	a := &AdaptiveBatchSize{Min: 10, Max: 100, Initial: 20, TargetLatency: 100 * time.Millisecond}
+
+	if got := a.Limit(); got != 20 {
+		t.Fatalf("expected initial limit 20, got %d", got)
+	}
+
+	a.Observe(20, 20, 10*time.Millisecond, nil)
+	if got := a.Limit(); got != 40 {
+		t.Errorf("expected limit to double to 40, got %d", got)
+	}
+
+	a.Observe(40, 40, 10*time.Millisecond, nil)
+	a.Observe(80, 80, 10*time.Millisecond, nil)
+	if got := a.Limit(); got != 100 {
+		t.Errorf("expected limit capped at Max 100, got %d", got)
+	}
+}
+
+func TestAdaptiveBatchSizeKeepsSizeWhenNotFull(t *testing.T) {
+	a := &AdaptiveBatchSize{Min: 10, Max: 100, Initial: 20, TargetLatency: 100 * time.Millisecond}
+
+	a.Observe(5, 20, 10*time.Millisecond, nil)
+	if got := a.Limit(); got != 20 {
+		t.Errorf("expected limit to stay at 20 for a partial batch, got %d", got)
+	}
+}
+
+func TestAdaptiveBatchSizeShrinksWhenSlow(t *testing.T) {
+	a := &AdaptiveBatchSize{Min: 10, Max: 100, Initial: 80, TargetLatency: 100 * time.Millisecond}
+
+	a.Observe(80, 80, 200*time.Millisecond, nil)
+	if got := a.Limit(); got != 40 {
+		t.Errorf("expected limit to halve to 40, got %d", got)
+	}
+
+	a.Observe(40, 40, 200*time.Millisecond, nil)
+	a.Observe(20, 20, 200*time.Millisecond, nil)
+	if got := a.Limit(); got != 10 {
+		t.Errorf("expected limit floored at Min 10, got %d", got)
+	}
+}
+
+func TestAdaptiveBatchSizeShrinksOnTimeout(t *testing.T) {
+	a := &AdaptiveBatchSize{Min: 10, Max: 100, Initial: 80, TargetLatency: time.Second}
+
+	err := fmt.Errorf("apply: %w", context.DeadlineExceeded)
+	a.Observe(80, 80, 10*time.Millisecond, err)
+	if got := a.Limit(); got != 40 {
+		t.Errorf("expected limit to halve on timeout, got %d", got)
+	}
+
+	a.Observe(40, 40, 10*time.Millisecond, errors.New("constraint violation"))
+	if got := a.Limit(); got != 40 {
+		t.Errorf("expected non-timeout error to keep limit, got %d", got)
+	}
+}
+
+func TestWorkerBatchSizerAndStatus(t *testing.T) {
+	consumer := newFakeConsumer()
+	// Batches of 1, 2 and 4 events fill the limits 1, 2 and 4
+	id := 0
+	for i, size := range []int{1, 2, 4} {
+		var batch []es.Envelope
+		for j := 0; j < size; j++ {
+			id++
+			batch = append(batch, createTestEvent(fmt.Sprint(id), "event"))
+		}
+		consumer.AddBatch(batch, es.Cursor(fmt.Sprintf("cursor%d", i)))
+	}
+
+	sizer := &AdaptiveBatchSize{Min: 1, Max: 4, Initial: 1, TargetLatency: time.Second}
+	worker := &Worker{
+		Source:     consumer,
+		Start:      es.Cursor("start"),
+		BatchSizer: sizer,
+		IdleSleep:  time.Millisecond,
+		Apply: func(ctx context.Context, batch []es.Envelope, next es.Cursor) error {
+			return nil
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if err := worker.Run(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+
+	// Every batch came back full and fast, so the limit doubles until Max
+	wantLimits := []int{1, 2, 4, 4}
+	for i, want := range wantLimits {
+		if got := consumer.fetchCalls[i].limit; got != want {
+			t.Errorf("fetch %d: expected limit %d, got %d", i, want, got)
+		}
+	}
+
+	status := worker.Status()
+	if status.Running {
+		t.Error("expected Running=false after Run returned")
+	}
+	if status.BatchSize != 4 {
+		t.Errorf("expected status batch size 4, got %d", status.BatchSize)
+	}
+	if status.Batches != 3 || status.Events != 7 {
+		t.Errorf("expected 3 batches / 7 events, got %d / %d", status.Batches, status.Events)
+	}
+	if string(status.Cursor) != "cursor2" {
+		t.Errorf("expected status cursor 'cursor2', got %q", status.Cursor)
+	}
+}
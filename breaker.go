@@ -0,0 +1,161 @@
+package projector
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// BreakerState is the state of a CircuitBreaker.
+type BreakerState int
+
+const (
+	// BreakerClosed lets the worker fetch and apply normally.
+	BreakerClosed BreakerState = iota
+	// BreakerOpen pauses fetching until the cool-down has elapsed.
+	BreakerOpen
+	// BreakerHalfOpen lets trial batches through; success closes the circuit, failure reopens it.
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("BreakerState(%d)", int(s))
+	}
+}
+
+// BreakerTransition describes a CircuitBreaker state change.
+type BreakerTransition struct {
+	From, To BreakerState
+	Failures int   // consecutive Apply failures when the transition happened
+	Err      error // the Apply error that opened the circuit; nil otherwise
+}
+
+// CircuitBreaker protects the projection target from a worker (and whatever restarts it)
+// retrying Apply against a target that is down. Apply failures still make Run return,
+// but once FailureThreshold consecutive batches failed the circuit opens and Run stops
+// fetching until CoolDown has elapsed, so no batches are pulled that cannot be applied
+// and the cursor stays put. Keep the breaker on the Worker across restarts; a Supervisor
+// does not count the Apply failure that opens the circuit towards a crash loop. It is safe
+// to share between workers writing to the same target.
+type CircuitBreaker struct {
+	FailureThreshold int           // consecutive Apply failures that open the circuit; default: 5
+	SuccessThreshold int           // successful trial batches that close it again; default: 1
+	CoolDown         time.Duration // how long the circuit stays open; default: 30s
+
+	mu        sync.Mutex
+	state     BreakerState
+	failures  int
+	successes int
+	openedAt  time.Time
+}
+
+// State returns the current state.
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+func (b *CircuitBreaker) coolDown() time.Duration {
+	if b.CoolDown <= 0 {
+		return 30 * time.Second
+	}
+	return b.CoolDown
+}
+
+// remaining returns how long an open circuit stays open, or 0 if fetching may proceed
+func (b *CircuitBreaker) remaining(now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state != BreakerOpen {
+		return 0
+	}
+	if d := b.openedAt.Add(b.coolDown()).Sub(now); d > 0 {
+		return d
+	}
+	return 0
+}
+
+// allow moves an open circuit whose cool-down has elapsed to half-open
+func (b *CircuitBreaker) allow(now time.Time) (BreakerTransition, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state != BreakerOpen || now.Before(b.openedAt.Add(b.coolDown())) {
+		return BreakerTransition{}, false
+	}
+	b.state, b.successes = BreakerHalfOpen, 0
+	return BreakerTransition{From: BreakerOpen, To: BreakerHalfOpen, Failures: b.failures}, true
+}
+
+// record updates the breaker with the outcome of an Apply call
+func (b *CircuitBreaker) record(err error, now time.Time) (BreakerTransition, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	from := b.state
+	if err == nil {
+		b.failures = 0
+		if b.state != BreakerHalfOpen {
+			return BreakerTransition{}, false
+		}
+		b.successes++
+		threshold := b.SuccessThreshold
+		if threshold <= 0 {
+			threshold = 1
+		}
+		if b.successes < threshold {
+			return BreakerTransition{}, false
+		}
+		b.state = BreakerClosed
+		return BreakerTransition{From: from, To: BreakerClosed}, true
+	}
+
+	b.failures++
+	threshold := b.FailureThreshold
+	if threshold <= 0 {
+		threshold = 5
+	}
+	if b.state == BreakerOpen || (b.state == BreakerClosed && b.failures < threshold) {
+		return BreakerTransition{}, false
+	}
+	b.state, b.openedAt = BreakerOpen, now
+	return BreakerTransition{From: from, To: BreakerOpen, Failures: b.failures, Err: err}, true
+}
+
+// waitBreaker blocks while the circuit is open, then lets a trial batch through
+func (w *Worker) waitBreaker(ctx context.Context, r *runState) error {
+	if d := w.Breaker.remaining(r.clock.Now()); d > 0 {
+		w.logf("circuit open, pausing fetch", "coolDown", d)
+
+		timer := r.clock.NewTimer(d)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			w.logf("worker stopped due to context cancellation while circuit open")
+			return ctx.Err()
+		case <-timer.C():
+		}
+	}
+
+	if t, ok := w.Breaker.allow(r.clock.Now()); ok {
+		w.reportBreaker(t)
+	}
+	return nil
+}
+
+// reportBreaker logs a breaker state change and forwards it to the hook
+func (w *Worker) reportBreaker(t BreakerTransition) {
+	w.logf("circuit breaker state changed", "from", t.From, "to", t.To, "failures", t.Failures)
+	if w.Hooks.OnBreakerStateChange != nil {
+		w.Hooks.OnBreakerStateChange(t)
+	}
+}
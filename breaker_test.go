@@ -0,0 +1,110 @@
+package projector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	es "github.com/shogotsuneto/go-simple-eventstore"
+)
+
+func TestCircuitBreakerTransitions(t *testing.T) {
+	b := &CircuitBreaker{FailureThreshold: 2, SuccessThreshold: 2, CoolDown: time.Minute}
+	now := time.Unix(0, 0)
+	fail := errors.New("db down")
+
+	var got []string
+	step := func(tr BreakerTransition, ok bool) {
+		if ok {
+			got = append(got, tr.From.String()+">"+tr.To.String())
+		}
+	}
+
+	step(b.record(fail, now))
+	step(b.record(nil, now)) // success resets the failure count
+	step(b.record(fail, now))
+	step(b.record(fail, now)) // opens
+	if d := b.remaining(now.Add(10 * time.Second)); d != 50*time.Second {
+		t.Errorf("expected 50s of cool-down left, got %v", d)
+	}
+	step(b.allow(now.Add(30 * time.Second))) // still cooling down
+	step(b.allow(now.Add(time.Minute)))      // half-open
+	step(b.record(fail, now.Add(time.Minute)))
+	step(b.allow(now.Add(2 * time.Minute)))
+	step(b.record(nil, now.Add(2*time.Minute)))
+	step(b.record(nil, now.Add(2*time.Minute))) // second success closes
+
+	want := "closed>open open>half-open half-open>open open>half-open half-open>closed"
+	if strings.Join(got, " ") != want {
+		t.Errorf("expected transitions %q, got %q", want, strings.Join(got, " "))
+	}
+	if b.State() != BreakerClosed {
+		t.Errorf("expected closed, got %s", b.State())
+	}
+}
+
+func TestWorkerBreakerPausesFetchingWhileOpen(t *testing.T) {
+	consumer := newFakeConsumer()
+	for i := 1; i <= 3; i++ {
+		consumer.AddBatch([]es.Envelope{createTestEvent("1", "e")}, es.Cursor("cursor1"))
+	}
+
+	clock := &stubClock{now: time.Unix(0, 0)}
+	down := true
+	var transitions []string
+	worker := &Worker{
+		Source:    consumer,
+		Start:     es.Cursor("start"),
+		IdleSleep: time.Millisecond,
+		Clock:     clock,
+		Breaker:   &CircuitBreaker{FailureThreshold: 2, CoolDown: time.Minute},
+		Hooks: Hooks{OnBreakerStateChange: func(t BreakerTransition) {
+			transitions = append(transitions, fmt.Sprintf("%s>%s", t.From, t.To))
+		}},
+		Apply: func(ctx context.Context, batch []es.Envelope, next es.Cursor) error {
+			if down {
+				return errors.New("db down")
+			}
+			return nil
+		},
+	}
+
+	// Two failing runs (as a restart loop would do) open the circuit
+	for i := 0; i < 2; i++ {
+		if err := worker.Run(context.Background()); err == nil {
+			t.Fatal("expected apply error")
+		}
+	}
+	if worker.Breaker.State() != BreakerOpen {
+		t.Fatalf("expected open circuit, got %s", worker.Breaker.State())
+	}
+
+	// While open, Run waits out the cool-down without fetching
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := worker.Run(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if len(consumer.fetchCalls) != 2 {
+		t.Errorf("expected no fetch while open, got %d fetches", len(consumer.fetchCalls))
+	}
+
+	// After the cool-down a trial batch is let through and closes the circuit
+	down = false
+	clock.now = clock.now.Add(time.Minute)
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := worker.Run(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+
+	if got := strings.Join(transitions, " "); got != "closed>open open>half-open half-open>closed" {
+		t.Errorf("unexpected transitions: %s", got)
+	}
+	if len(consumer.commitCalls) != 1 {
+		t.Errorf("expected only the trial batch to be committed, got %d commits", len(consumer.commitCalls))
+	}
+}
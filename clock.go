@@ -0,0 +1,57 @@
+package projector
+
+import (
+	"context"
+	"time"
+)
+
+// Clock abstracts time so idle sleeps, backoffs and timeouts can be tested
+// deterministically. Worker uses the real clock when Clock is nil; see
+// projectortest.FakeClock for a manually advanced implementation.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer is the subset of *time.Timer used by the worker.
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+}
+
+// realClock implements Clock with the time package
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+func (realClock) NewTimer(d time.Duration) Timer         { return realTimer{time.NewTimer(d)} }
+
+// realTimer adapts *time.Timer to Timer
+type realTimer struct{ t *time.Timer }
+
+func (r realTimer) C() <-chan time.Time { return r.t.C }
+func (r realTimer) Stop() bool          { return r.t.Stop() }
+
+// clock returns the configured clock or the real one
+func (w *Worker) clock() Clock {
+	if w.Clock != nil {
+		return w.Clock
+	}
+	return realClock{}
+}
+
+type clockKey struct{}
+
+// withClock passes the worker's clock to middlewares
+func withClock(ctx context.Context, c Clock) context.Context {
+	return context.WithValue(ctx, clockKey{}, c)
+}
+
+// clockFrom returns the clock of the worker calling Apply, or the real one
+func clockFrom(ctx context.Context) Clock {
+	if c, ok := ctx.Value(clockKey{}).(Clock); ok {
+		return c
+	}
+	return realClock{}
+}
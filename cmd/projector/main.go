@@ -0,0 +1,187 @@
+// Command projector provides tooling around projections.
+//
+// Usage:
+//
+//	projector export -source <dsn|path> [flags]
+//
+// export writes the events of a source as JSON Lines (see package jsonl) from a cursor
+// up to the current end, then prints the cursor to resume from. The source is either a
+// Postgres connection string (go-simple-eventstore's events table) or a JSONL file or
+// directory.
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/shogotsuneto/go-simple-es-projector"
+	"github.com/shogotsuneto/go-simple-es-projector/internal/source"
+	"github.com/shogotsuneto/go-simple-es-projector/jsonl"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if len(os.Args) < 2 {
+		usage(os.Stderr)
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "export":
+		err = runExport(ctx, os.Args[2:], os.Stdout, os.Stderr)
+	case "-h", "-help", "--help", "help":
+		usage(os.Stdout)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "projector: unknown command %q\n", os.Args[1])
+		usage(os.Stderr)
+		os.Exit(2)
+	}
+
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "projector: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: projector <command> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	fmt.Fprintln(w, "  export   write a source's events as JSON Lines and print the resume cursor")
+}
+
+// runExport implements the export subcommand; the resume cursor goes to stderr so
+// stdout can carry the events
+func runExport(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	spec := fs.String("source", "", "postgres:// connection string, or a JSONL file or directory (required)")
+	table := fs.String("table", "events", "events table for Postgres sources")
+	output := fs.String("o", "-", "output file; - for stdout")
+	gzipOut := fs.Bool("gzip", false, "gzip-compress the output")
+	from := fs.String("cursor", "", "hex cursor to start after (as printed by a previous export)")
+	types := fs.String("type", "", "comma-separated event type globs to keep, e.g. product.*")
+	streams := fs.String("stream", "", "comma-separated stream ID globs to keep, e.g. product-*")
+	since := fs.String("since", "", "keep events at or after this RFC 3339 time")
+	until := fs.String("until", "", "keep events before this RFC 3339 time")
+	batchSize := fs.Int("batch", 256, "events per fetch")
+	verbose := fs.Bool("v", false, "log worker progress to stderr")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *spec == "" {
+		fs.Usage()
+		return errors.New("export: -source is required")
+	}
+
+	start, err := hex.DecodeString(*from)
+	if err != nil {
+		return fmt.Errorf("export: invalid -cursor: %w", err)
+	}
+	filter, err := exportFilter(*types, *streams, *since, *until)
+	if err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+
+	src, closeSrc, err := source.Open(*spec, *table)
+	if err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+	defer closeSrc()
+
+	out := stdout
+	var f *os.File
+	if *output != "-" {
+		f, err = os.Create(*output)
+		if err != nil {
+			return fmt.Errorf("export: %w", err)
+		}
+		out = f
+	}
+
+	opts := jsonl.ExportOptions{
+		Start:     start,
+		Filter:    filter,
+		Gzip:      *gzipOut,
+		BatchSize: *batchSize,
+	}
+	if *verbose {
+		logger := log.New(stderr, "", log.LstdFlags)
+		opts.Logger = func(msg string, kv ...any) { logger.Printf("%s %v", msg, kv) }
+	}
+
+	res, err := jsonl.Export(ctx, src, out, opts)
+	if f != nil {
+		// Data may be lost when close fails, so print no resume cursor past it
+		if cerr := f.Close(); cerr != nil {
+			return fmt.Errorf("export: %w", cerr)
+		}
+	}
+	fmt.Fprintf(stderr, "exported %d events\nresume cursor: %x\n", res.Events, res.Cursor)
+	if err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+	return nil
+}
+
+// exportFilter combines the filter flags; nil keeps everything
+func exportFilter(types, streams, since, until string) (projector.Filter, error) {
+	var filters []projector.Filter
+	if f := globs(types, projector.TypeMatches); f != nil {
+		filters = append(filters, f)
+	}
+	if f := globs(streams, projector.StreamMatches); f != nil {
+		filters = append(filters, f)
+	}
+
+	var sinceT, untilT time.Time
+	var err error
+	if since != "" {
+		if sinceT, err = time.Parse(time.RFC3339Nano, since); err != nil {
+			return nil, fmt.Errorf("invalid -since: %w", err)
+		}
+	}
+	if until != "" {
+		if untilT, err = time.Parse(time.RFC3339Nano, until); err != nil {
+			return nil, fmt.Errorf("invalid -until: %w", err)
+		}
+	}
+	if !sinceT.IsZero() || !untilT.IsZero() {
+		filters = append(filters, projector.TimeRange(sinceT, untilT))
+	}
+
+	if len(filters) == 0 {
+		return nil, nil
+	}
+	return projector.All(filters...), nil
+}
+
+// globs builds a filter keeping events matching any of the comma-separated patterns
+func globs(list string, match func(pattern string) projector.Filter) projector.Filter {
+	var filters []projector.Filter
+	for _, p := range strings.Split(list, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			filters = append(filters, match(p))
+		}
+	}
+	if len(filters) == 0 {
+		return nil
+	}
+	return projector.Any(filters...)
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunExport(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "in.jsonl")
+	lines := `{"id":"1","stream_id":"product-1","type":"product.tag_added","timestamp":"2024-05-01T10:00:00Z"}` + "\n" +
+		`{"id":"2","stream_id":"order-1","type":"order.placed","timestamp":"2024-05-01T11:00:00Z"}` + "\n" +
+		`{"id":"3","stream_id":"product-2","type":"product.renamed","timestamp":"2024-05-02T10:00:00Z"}` + "\n"
+	if err := os.WriteFile(in, []byte(lines), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	err := runExport(context.Background(), []string{
+		"-source", in, "-type", "product.*, order.*", "-until", "2024-05-02T00:00:00Z",
+	}, &stdout, &stderr)
+	if err != nil {
+		t.Fatalf("unexpected error: %v (%s)", err, stderr.String())
+	}
+	if got := strings.Count(stdout.String(), "\n"); got != 2 || strings.Contains(stdout.String(), `"id":"3"`) {
+		t.Errorf("expected events 1 and 2, got %q", stdout.String())
+	}
+
+	// The printed cursor resumes after everything read, including filtered events
+	var resume string
+	for _, l := range strings.Split(stderr.String(), "\n") {
+		if c, ok := strings.CutPrefix(l, "resume cursor: "); ok {
+			resume = c
+		}
+	}
+	if resume == "" {
+		t.Fatalf("expected a resume cursor, got %q", stderr.String())
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	if err := runExport(context.Background(), []string{"-source", in, "-cursor", resume}, &stdout, &stderr); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stdout.Len() != 0 || !strings.Contains(stderr.String(), "exported 0 events") {
+		t.Errorf("expected nothing new after the resume cursor, got %q / %q", stdout.String(), stderr.String())
+	}
+}
+
+func TestRunExportRejectsBadFlags(t *testing.T) {
+	tests := [][]string{
+		{},
+		{"-source", "in.jsonl", "-cursor", "zz"},
+		{"-source", "in.jsonl", "-since", "yesterday"},
+	}
+	for _, args := range tests {
+		var stdout, stderr bytes.Buffer
+		if err := runExport(context.Background(), args, &stdout, &stderr); err == nil {
+			t.Errorf("expected an error for %q", args)
+		}
+	}
+}
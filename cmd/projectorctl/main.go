@@ -0,0 +1,380 @@
+// Command projectorctl inspects and manipulates projection checkpoints, stored in the
+// Postgres layout of examples/pg_to_pg (see postgres.Checkpoints).
+//
+// Usage:
+//
+//	projectorctl list                                  list projections and their cursors
+//	projectorctl lag [-name n]                         events each projection is behind the source
+//	projectorctl reset -name n (-start | -cursor hex | -time t) -yes
+//	projectorctl copy -from a -to b [-yes]             -yes is needed to overwrite b
+//
+// The checkpoint database defaults to $PROJECTION_URL and the event source to
+// $EVENTSTORE_URL (a Postgres connection string, or a JSONL file or directory). Stop
+// the affected workers before reset or copy: the new cursor bypasses their transactions.
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"text/tabwriter"
+	"time"
+
+	"github.com/shogotsuneto/go-simple-es-projector"
+	"github.com/shogotsuneto/go-simple-es-projector/cursor"
+	"github.com/shogotsuneto/go-simple-es-projector/internal/source"
+	pgprojector "github.com/shogotsuneto/go-simple-es-projector/postgres"
+	es "github.com/shogotsuneto/go-simple-eventstore"
+)
+
+// errNotConfirmed is returned by destructive commands run without -yes
+var errNotConfirmed = errors.New("not confirmed; rerun with -yes to apply")
+
+// app holds the command's I/O; tests replace the open functions with in-memory ones
+type app struct {
+	stdout, stderr io.Writer
+	openStore      func(dsn, table string) (projector.CheckpointAdmin, func(), error)
+	openSource     func(spec, table string) (es.Consumer, func(), error)
+}
+
+// config holds the connection flags shared by all commands
+type config struct {
+	db, table, source, eventsTable string
+	batch                          int
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	a := &app{stdout: os.Stdout, stderr: os.Stderr, openStore: openStore, openSource: source.Open}
+	err := a.run(ctx, os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "projectorctl: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func (a *app) run(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		a.usage()
+		return errors.New("missing command")
+	}
+	switch args[0] {
+	case "list":
+		return a.list(ctx, args[1:])
+	case "lag":
+		return a.lag(ctx, args[1:])
+	case "reset":
+		return a.reset(ctx, args[1:])
+	case "copy":
+		return a.copy(ctx, args[1:])
+	case "-h", "-help", "--help", "help":
+		a.usage()
+		return nil
+	default:
+		a.usage()
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
+
+func (a *app) usage() {
+	fmt.Fprintln(a.stderr, "Usage: projectorctl <command> [flags]")
+	fmt.Fprintln(a.stderr)
+	fmt.Fprintln(a.stderr, "Commands:")
+	fmt.Fprintln(a.stderr, "  list    list projections and their cursors")
+	fmt.Fprintln(a.stderr, "  lag     show how many events each projection is behind the source")
+	fmt.Fprintln(a.stderr, "  reset   move a projection to the beginning, a cursor or a timestamp (needs -yes)")
+	fmt.Fprintln(a.stderr, "  copy    copy a checkpoint to another projection name")
+}
+
+// flags returns a flag set with the shared connection flags registered
+func (a *app) flags(name string) (*flag.FlagSet, *config) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(a.stderr)
+	cfg := &config{}
+	fs.StringVar(&cfg.db, "db", os.Getenv("PROJECTION_URL"), "checkpoint database connection string")
+	fs.StringVar(&cfg.table, "table", "projection_checkpoints", "checkpoint table")
+	fs.StringVar(&cfg.source, "source", os.Getenv("EVENTSTORE_URL"), "event source: postgres:// connection string, or a JSONL file or directory")
+	fs.StringVar(&cfg.eventsTable, "events-table", "events", "events table for Postgres sources")
+	fs.IntVar(&cfg.batch, "batch", 1000, "events per fetch when scanning the source")
+	return fs, cfg
+}
+
+func (a *app) store(cfg *config) (projector.CheckpointAdmin, func(), error) {
+	if cfg.db == "" {
+		return nil, nil, errors.New("no checkpoint database: set -db or PROJECTION_URL")
+	}
+	return a.openStore(cfg.db, cfg.table)
+}
+
+func (a *app) source(cfg *config) (es.Consumer, func(), error) {
+	if cfg.source == "" {
+		return nil, nil, errors.New("no event source: set -source or EVENTSTORE_URL")
+	}
+	return a.openSource(cfg.source, cfg.eventsTable)
+}
+
+func (a *app) list(ctx context.Context, args []string) error {
+	fs, cfg := a.flags("list")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	store, closeStore, err := a.store(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeStore()
+
+	cps, err := store.List(ctx)
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(a.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tUPDATED\tCURSOR\tPOSITION")
+	for _, cp := range cps {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", cp.Name, cp.UpdatedAt.Format(time.RFC3339), hexCursor(cp.Cursor), cursor.Describe(cp.Cursor))
+	}
+	return tw.Flush()
+}
+
+func (a *app) lag(ctx context.Context, args []string) error {
+	fs, cfg := a.flags("lag")
+	name := fs.String("name", "", "only this projection")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	store, closeStore, err := a.store(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeStore()
+	src, closeSrc, err := a.source(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeSrc()
+
+	cps, err := store.List(ctx)
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(a.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tBEHIND\tOLDEST PENDING")
+	found := false
+	for _, cp := range cps {
+		if *name != "" && cp.Name != *name {
+			continue
+		}
+		found = true
+		behind, oldest, err := countAfter(ctx, src, cp.Cursor, cfg.batch)
+		if err != nil {
+			return fmt.Errorf("%s: %w", cp.Name, err)
+		}
+		age := "-"
+		if behind > 0 && !oldest.IsZero() {
+			age = time.Since(oldest).Round(time.Second).String()
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\n", cp.Name, behind, age)
+	}
+	if *name != "" && !found {
+		return fmt.Errorf("no checkpoint named %q", *name)
+	}
+	return tw.Flush()
+}
+
+func (a *app) reset(ctx context.Context, args []string) error {
+	fs, cfg := a.flags("reset")
+	name := fs.String("name", "", "projection to reset (required)")
+	toStart := fs.Bool("start", false, "reset to the beginning of the source")
+	toCursor := fs.String("cursor", "", "reset to this hex cursor")
+	toTime := fs.String("time", "", "reset to the first event at or after this RFC 3339 time (scans the source)")
+	yes := fs.Bool("yes", false, "confirm the reset")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *name == "" {
+		return errors.New("reset: -name is required")
+	}
+	targets := 0
+	for _, set := range []bool{*toStart, *toCursor != "", *toTime != ""} {
+		if set {
+			targets++
+		}
+	}
+	if targets != 1 {
+		return errors.New("reset: specify exactly one of -start, -cursor or -time")
+	}
+
+	store, closeStore, err := a.store(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeStore()
+
+	target := es.Cursor{}
+	switch {
+	case *toCursor != "":
+		if target, err = hex.DecodeString(*toCursor); err != nil {
+			return fmt.Errorf("reset: invalid -cursor: %w", err)
+		}
+	case *toTime != "":
+		t, err := time.Parse(time.RFC3339Nano, *toTime)
+		if err != nil {
+			return fmt.Errorf("reset: invalid -time: %w", err)
+		}
+		src, closeSrc, err := a.source(cfg)
+		if err != nil {
+			return err
+		}
+		defer closeSrc()
+		if target, err = cursorAt(ctx, src, t, cfg.batch); err != nil {
+			return fmt.Errorf("reset: %w", err)
+		}
+	}
+
+	current, err := store.Load(ctx, *name)
+	if err != nil {
+		return err
+	}
+	return a.put(ctx, store, *name, current, target, *yes)
+}
+
+func (a *app) copy(ctx context.Context, args []string) error {
+	fs, cfg := a.flags("copy")
+	from := fs.String("from", "", "projection to copy from (required)")
+	to := fs.String("to", "", "projection to copy to (required)")
+	yes := fs.Bool("yes", false, "confirm overwriting an existing checkpoint")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *from == "" || *to == "" {
+		return errors.New("copy: -from and -to are required")
+	}
+
+	store, closeStore, err := a.store(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeStore()
+
+	src, err := store.Load(ctx, *from)
+	if err != nil {
+		return err
+	}
+	if src == nil {
+		return fmt.Errorf("copy: no checkpoint named %q", *from)
+	}
+	current, err := store.Load(ctx, *to)
+	if err != nil {
+		return err
+	}
+	// Creating a new checkpoint destroys nothing
+	return a.put(ctx, store, *to, current, src, *yes || current == nil)
+}
+
+// put moves name from current to target, or only prints the change when not confirmed
+func (a *app) put(ctx context.Context, store projector.CheckpointAdmin, name string, current, target es.Cursor, confirmed bool) error {
+	change := fmt.Sprintf("%s: %s -> %s", name, hexCursor(current), hexCursor(target))
+	if current == nil {
+		change = fmt.Sprintf("%s: (none) -> %s", name, hexCursor(target))
+	}
+	if !confirmed {
+		fmt.Fprintf(a.stdout, "would set %s\n", change)
+		return errNotConfirmed
+	}
+	if err := store.Put(ctx, name, target); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.stdout, "set %s\n", change)
+	return nil
+}
+
+// countAfter counts the events after from, and returns the timestamp of the first one.
+// Sources implementing source.Counter are asked for the count; others are scanned.
+func countAfter(ctx context.Context, src es.Consumer, from es.Cursor, batch int) (int64, time.Time, error) {
+	if c, ok := src.(source.Counter); ok {
+		n, err := c.Behind(ctx, from)
+		if err != nil || n == 0 {
+			return n, time.Time{}, err
+		}
+		first, _, err := src.Fetch(ctx, from, 1)
+		if err != nil || len(first) == 0 {
+			return n, time.Time{}, err
+		}
+		return n, first[0].Event.Timestamp, nil
+	}
+
+	var n int64
+	var oldest time.Time
+	for {
+		events, next, err := src.Fetch(ctx, from, batch)
+		if err != nil {
+			return 0, time.Time{}, err
+		}
+		if len(events) == 0 {
+			return n, oldest, nil
+		}
+		if n == 0 {
+			oldest = events[0].Event.Timestamp
+		}
+		n += int64(len(events))
+		from = next
+	}
+}
+
+// cursorAt returns the cursor after the last event before t, scanning from the
+// beginning; events are assumed to be in timestamp order
+func cursorAt(ctx context.Context, src es.Consumer, t time.Time, batch int) (es.Cursor, error) {
+	from := es.Cursor{}
+	for {
+		events, next, err := src.Fetch(ctx, from, batch)
+		if err != nil {
+			return nil, err
+		}
+		if len(events) == 0 {
+			return from, nil // every event is before t
+		}
+		for i, ev := range events {
+			if ev.Event.Timestamp.Before(t) {
+				continue
+			}
+			if i == 0 {
+				return from, nil
+			}
+			// Refetch exactly the events before t to get the cursor after them
+			_, next, err := src.Fetch(ctx, from, i)
+			return next, err
+		}
+		from = next
+	}
+}
+
+// hexCursor renders a cursor as accepted by -cursor; "-" is the beginning
+func hexCursor(c es.Cursor) string {
+	if len(c) == 0 {
+		return "-"
+	}
+	return hex.EncodeToString(c)
+}
+
+func openStore(dsn, table string) (projector.CheckpointAdmin, func(), error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := db.Ping(); err != nil {
+		_ = db.Close()
+		return nil, nil, fmt.Errorf("checkpoint database: %w", err)
+	}
+	return &pgprojector.Checkpoints{DB: db, Table: table}, func() { _ = db.Close() }, nil
+}
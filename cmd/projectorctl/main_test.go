@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shogotsuneto/go-simple-es-projector"
+	"github.com/shogotsuneto/go-simple-es-projector/memory"
+	es "github.com/shogotsuneto/go-simple-eventstore"
+)
+
+// newTestApp returns an app backed by in-memory checkpoints and a source of 5 events,
+// one per hour from 2024-05-01T00:00:00Z
+func newTestApp(t *testing.T) (*app, *memory.Checkpoints, *bytes.Buffer) {
+	t.Helper()
+	store := &memory.Checkpoints{}
+	src := &memory.Consumer{}
+	base := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		src.Append("product-1", es.Event{Type: "product.tag_added", Timestamp: base.Add(time.Duration(i) * time.Hour)})
+	}
+
+	var stdout bytes.Buffer
+	a := &app{
+		stdout: &stdout,
+		stderr: &bytes.Buffer{},
+		openStore: func(dsn, table string) (projector.CheckpointAdmin, func(), error) {
+			return store, func() {}, nil
+		},
+		openSource: func(spec, table string) (es.Consumer, func(), error) {
+			return src, func() {}, nil
+		},
+	}
+	return a, store, &stdout
+}
+
+func run(a *app, args ...string) error {
+	return a.run(context.Background(), append(args, "-db", "test", "-source", "test"))
+}
+
+func TestListAndLag(t *testing.T) {
+	a, store, out := newTestApp(t)
+	ctx := context.Background()
+	_ = store.Put(ctx, "product_tags", memory.Cursor(2))
+	_ = store.Put(ctx, "search", memory.Cursor(5))
+
+	if err := run(a, "list"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), "product_tags") || !strings.Contains(out.String(), "0200000000000000") {
+		t.Errorf("expected projections with hex cursors, got:\n%s", out)
+	}
+
+	out.Reset()
+	if err := run(a, "lag"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 || strings.Fields(lines[1])[1] != "3" || strings.Fields(lines[2])[1] != "0" {
+		t.Errorf("expected product_tags 3 behind and search caught up, got:\n%s", out)
+	}
+
+	if err := run(a, "lag", "-name", "missing"); err == nil {
+		t.Error("expected an error for an unknown projection")
+	}
+}
+
+// countingSource is a source that counts without scanning, like a Postgres source
+type countingSource struct {
+	*memory.Consumer
+	fetches int
+}
+
+func (s *countingSource) Fetch(ctx context.Context, cursor es.Cursor, limit int) ([]es.Envelope, es.Cursor, error) {
+	s.fetches++
+	return s.Consumer.Fetch(ctx, cursor, limit)
+}
+
+func (s *countingSource) Behind(ctx context.Context, cursor es.Cursor) (int64, error) {
+	return int64(s.Len()) - int64(binary.LittleEndian.Uint64(cursor)), nil
+}
+
+func TestLagAsksCountingSources(t *testing.T) {
+	a, store, out := newTestApp(t)
+	ctx := context.Background()
+	_ = store.Put(ctx, "product_tags", memory.Cursor(1))
+
+	src := &countingSource{Consumer: &memory.Consumer{}}
+	for i := 0; i < 5; i++ {
+		src.Append("product-1", es.Event{Type: "product.tag_added"})
+	}
+	a.openSource = func(spec, table string) (es.Consumer, func(), error) {
+		return src, func() {}, nil
+	}
+
+	if err := run(a, "lag", "-batch", "1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 || strings.Fields(lines[1])[1] != "4" {
+		t.Errorf("expected product_tags 4 behind, got:\n%s", out)
+	}
+	if src.fetches != 1 {
+		t.Errorf("expected a single fetch for the oldest pending event instead of a scan, got %d", src.fetches)
+	}
+}
+
+func TestResetRequiresConfirmation(t *testing.T) {
+	a, store, out := newTestApp(t)
+	ctx := context.Background()
+	_ = store.Put(ctx, "product_tags", memory.Cursor(4))
+
+	if err := run(a, "reset", "-name", "product_tags", "-start"); !errors.Is(err, errNotConfirmed) {
+		t.Fatalf("expected errNotConfirmed, got %v", err)
+	}
+	if cur, _ := store.Load(ctx, "product_tags"); string(cur) != string(memory.Cursor(4)) {
+		t.Errorf("expected checkpoint unchanged without -yes, got %x", cur)
+	}
+	if !strings.Contains(out.String(), "would set product_tags: 0400000000000000 -> -") {
+		t.Errorf("expected the planned change to be printed, got %q", out)
+	}
+
+	if err := run(a, "reset", "-name", "product_tags", "-start", "-yes"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cur, _ := store.Load(ctx, "product_tags"); cur == nil || len(cur) != 0 {
+		t.Errorf("expected an empty cursor, got %x", cur)
+	}
+
+	if err := run(a, "reset", "-name", "product_tags", "-start", "-cursor", "00", "-yes"); err == nil {
+		t.Error("expected an error for conflicting targets")
+	}
+}
+
+func TestResetToCursorAndTime(t *testing.T) {
+	a, store, _ := newTestApp(t)
+	ctx := context.Background()
+
+	if err := run(a, "reset", "-name", "product_tags", "-cursor", "0100000000000000", "-yes"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cur, _ := store.Load(ctx, "product_tags"); string(cur) != string(memory.Cursor(1)) {
+		t.Errorf("expected cursor 1, got %x", cur)
+	}
+
+	// Events 1-3 are before 02:30, so the projection resumes with event 4
+	if err := run(a, "reset", "-name", "product_tags", "-time", "2024-05-01T02:30:00Z", "-batch", "2", "-yes"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cur, _ := store.Load(ctx, "product_tags"); string(cur) != string(memory.Cursor(3)) {
+		t.Errorf("expected cursor 3, got %x", cur)
+	}
+}
+
+func TestCopy(t *testing.T) {
+	a, store, _ := newTestApp(t)
+	ctx := context.Background()
+	_ = store.Put(ctx, "product_tags", memory.Cursor(2))
+	_ = store.Put(ctx, "product_tags_v2", memory.Cursor(5))
+
+	if err := run(a, "copy", "-from", "product_tags", "-to", "product_tags_v3"); err != nil {
+		t.Fatalf("expected copying to a new name without -yes, got %v", err)
+	}
+	if cur, _ := store.Load(ctx, "product_tags_v3"); string(cur) != string(memory.Cursor(2)) {
+		t.Errorf("expected copied cursor 2, got %x", cur)
+	}
+
+	if err := run(a, "copy", "-from", "product_tags", "-to", "product_tags_v2"); !errors.Is(err, errNotConfirmed) {
+		t.Errorf("expected overwriting to need -yes, got %v", err)
+	}
+	if err := run(a, "copy", "-from", "missing", "-to", "other", "-yes"); err == nil {
+		t.Error("expected an error for an unknown source projection")
+	}
+}
@@ -0,0 +1,301 @@
+package cursor
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	es "github.com/shogotsuneto/go-simple-eventstore"
+)
+
+// Layout, all integers varints (uvarint unless noted):
+//
+//	v1: "ESM" | 1 | count | count × (len(name) name | skip | len(cursor) cursor)
+//	v2: "ESM" | 2 | records…, record = type(1 byte) | len(value) | value
+//
+// v2 record types:
+//
+//	1 position:  len(name) name | skip | cursor (rest of value)
+//	2 timestamp: unix nanoseconds (signed varint)
+//	3 events:    event count
+//	4 metadata:  len(key) key | value (rest of value)
+//
+// Records are written in a canonical order (by type, then name/key), so equal
+// composites encode to equal bytes.
+const (
+	magic   = "ESM"
+	version = 2
+
+	recPosition  = 1
+	recTimestamp = 2
+	recEvents    = 3
+	recMeta      = 4
+)
+
+// ErrInvalid is returned when bytes are not a composite cursor this package can read.
+var ErrInvalid = errors.New("cursor: invalid composite cursor")
+
+// Position is a named sub-cursor.
+type Position struct {
+	Cursor es.Cursor // the sub-source's own cursor
+	Skip   int       // events after Cursor already consumed (e.g. from a partially merged batch)
+}
+
+// Composite is the decoded form of a composite cursor.
+type Composite struct {
+	Version   int                 // set by Decode; Encode always writes the current version
+	Positions map[string]Position // sub-cursors by name
+	Timestamp time.Time           // optional; e.g. timestamp of the last delivered event
+	Events    int64               // optional; e.g. events delivered up to this cursor
+	Meta      map[string]string   // optional free-form metadata
+}
+
+// IsComposite reports whether c is a composite cursor this package can read. The
+// prefix alone is not enough: raw cursors, such as a little-endian row id, may start
+// with the same bytes.
+func IsComposite(c es.Cursor) bool {
+	if !hasMagic(c) {
+		return false
+	}
+	_, err := Decode(c)
+	return err == nil
+}
+
+// hasMagic reports whether c starts like a composite cursor
+func hasMagic(c es.Cursor) bool {
+	return len(c) > len(magic) && bytes.HasPrefix(c, []byte(magic))
+}
+
+// Encode writes c in the current version.
+func Encode(c Composite) es.Cursor {
+	buf := append([]byte(magic), version)
+
+	for _, name := range sortedKeys(c.Positions) {
+		p := c.Positions[name]
+		v := appendBytes(nil, []byte(name))
+		v = binary.AppendUvarint(v, uint64(p.Skip))
+		buf = appendRecord(buf, recPosition, append(v, p.Cursor...))
+	}
+	if !c.Timestamp.IsZero() {
+		buf = appendRecord(buf, recTimestamp, binary.AppendVarint(nil, c.Timestamp.UnixNano()))
+	}
+	if c.Events != 0 {
+		buf = appendRecord(buf, recEvents, binary.AppendUvarint(nil, uint64(c.Events)))
+	}
+	for _, key := range sortedKeys(c.Meta) {
+		v := appendBytes(nil, []byte(key))
+		buf = appendRecord(buf, recMeta, append(v, c.Meta[key]...))
+	}
+	return es.Cursor(buf)
+}
+
+// Decode parses a composite cursor of any supported version. An empty cursor decodes
+// to an empty Composite (every sub-source at its beginning).
+func Decode(c es.Cursor) (Composite, error) {
+	out := Composite{Version: version, Positions: map[string]Position{}}
+	if len(c) == 0 {
+		return out, nil
+	}
+	if !hasMagic(c) {
+		return Composite{}, ErrInvalid
+	}
+
+	out.Version = int(c[len(magic)])
+	r := bytes.NewReader(c[len(magic)+1:])
+	var err error
+	switch out.Version {
+	case 1:
+		err = decodeV1(r, &out)
+	case 2:
+		err = decodeV2(r, &out)
+	default:
+		return Composite{}, fmt.Errorf("%w: unsupported version %d", ErrInvalid, out.Version)
+	}
+	if err != nil {
+		return Composite{}, err
+	}
+	return out, nil
+}
+
+func decodeV1(r *bytes.Reader, out *Composite) error {
+	count, err := binary.ReadUvarint(r)
+	if err != nil || count > uint64(r.Len()) {
+		return ErrInvalid
+	}
+	for i := uint64(0); i < count; i++ {
+		name, err := readBytes(r)
+		if err != nil {
+			return err
+		}
+		skip, err := readSkip(r)
+		if err != nil {
+			return err
+		}
+		cur, err := readBytes(r)
+		if err != nil {
+			return err
+		}
+		out.Positions[string(name)] = Position{Cursor: cur, Skip: skip}
+	}
+	if r.Len() != 0 {
+		return ErrInvalid
+	}
+	return nil
+}
+
+// readSkip reads a position's skip count, bounded so it is a valid fetch limit
+func readSkip(r *bytes.Reader) (int, error) {
+	skip, err := binary.ReadUvarint(r)
+	if err != nil || skip > math.MaxInt32 {
+		return 0, ErrInvalid
+	}
+	return int(skip), nil
+}
+
+func decodeV2(r *bytes.Reader, out *Composite) error {
+	for r.Len() > 0 {
+		typ, _ := r.ReadByte()
+		if typ == 0 {
+			return ErrInvalid // never written; e.g. the zero bytes of a raw row id
+		}
+		value, err := readBytes(r)
+		if err != nil {
+			return err
+		}
+		v := bytes.NewReader(value)
+
+		switch typ {
+		case recPosition:
+			name, err := readBytes(v)
+			if err != nil {
+				return err
+			}
+			skip, err := readSkip(v)
+			if err != nil {
+				return err
+			}
+			out.Positions[string(name)] = Position{Cursor: rest(v), Skip: skip}
+		case recTimestamp:
+			ns, err := binary.ReadVarint(v)
+			if err != nil {
+				return ErrInvalid
+			}
+			out.Timestamp = time.Unix(0, ns).UTC()
+		case recEvents:
+			n, err := binary.ReadUvarint(v)
+			if err != nil || n > math.MaxInt64 {
+				return ErrInvalid
+			}
+			out.Events = int64(n)
+		case recMeta:
+			key, err := readBytes(v)
+			if err != nil {
+				return err
+			}
+			if out.Meta == nil {
+				out.Meta = map[string]string{}
+			}
+			out.Meta[string(key)] = string(rest(v))
+		default:
+			// Written by a newer version; skip it
+		}
+	}
+	return nil
+}
+
+// Describe renders a cursor for humans: composite cursors field by field, other
+// cursors (including ones that only start like a composite cursor) as quoted text
+// when printable or as hex otherwise.
+func Describe(c es.Cursor) string {
+	if len(c) == 0 {
+		return "<start>"
+	}
+	comp, err := Decode(c)
+	if err != nil {
+		return describeRaw(c)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "composite v%d", comp.Version)
+	if !comp.Timestamp.IsZero() {
+		fmt.Fprintf(&b, " at=%s", comp.Timestamp.Format(time.RFC3339Nano))
+	}
+	if comp.Events != 0 {
+		fmt.Fprintf(&b, " events=%d", comp.Events)
+	}
+	for _, name := range sortedKeys(comp.Positions) {
+		p := comp.Positions[name]
+		fmt.Fprintf(&b, " %s=%s", name, Describe(p.Cursor))
+		if p.Skip > 0 {
+			fmt.Fprintf(&b, "+%d", p.Skip)
+		}
+	}
+	for _, key := range sortedKeys(comp.Meta) {
+		fmt.Fprintf(&b, " %s=%q", key, comp.Meta[key])
+	}
+	return b.String()
+}
+
+func describeRaw(c es.Cursor) string {
+	if utf8.Valid(c) {
+		printable := true
+		for _, r := range string(c) {
+			if r < 0x20 || r == 0x7f {
+				printable = false
+				break
+			}
+		}
+		if printable {
+			return fmt.Sprintf("%q", string(c))
+		}
+	}
+	return fmt.Sprintf("0x%x", []byte(c))
+}
+
+func appendRecord(buf []byte, typ byte, value []byte) []byte {
+	buf = append(buf, typ)
+	return appendBytes(buf, value)
+}
+
+func appendBytes(buf, b []byte) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(b)))
+	return append(buf, b...)
+}
+
+func readBytes(r *bytes.Reader) ([]byte, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil || n > uint64(r.Len()) {
+		return nil, ErrInvalid
+	}
+	if n == 0 {
+		return nil, nil
+	}
+	b := make([]byte, n)
+	_, _ = r.Read(b)
+	return b, nil
+}
+
+// rest returns the unread part of r, or nil if nothing is left
+func rest(r *bytes.Reader) []byte {
+	if r.Len() == 0 {
+		return nil
+	}
+	b := make([]byte, r.Len())
+	_, _ = r.Read(b)
+	return b
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
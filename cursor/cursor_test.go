@@ -0,0 +1,138 @@
+package cursor
+
+import (
+	"encoding/binary"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	es "github.com/shogotsuneto/go-simple-eventstore"
+)
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	in := Composite{
+		Positions: map[string]Position{
+			"products": {Cursor: es.Cursor{1, 2, 3}, Skip: 2},
+			"orders":   {},
+		},
+		Timestamp: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		Events:    42,
+		Meta:      map[string]string{"shard": "3"},
+	}
+	c := Encode(in)
+
+	if !strings.HasPrefix(string(c), "ESM\x02") {
+		t.Fatalf("expected magic and version prefix, got %x", []byte(c))
+	}
+	// Equal composites encode to equal bytes
+	if string(Encode(in)) != string(c) {
+		t.Error("expected deterministic encoding")
+	}
+
+	out, err := Decode(c)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.Version != 2 || out.Events != 42 || !out.Timestamp.Equal(in.Timestamp) || out.Meta["shard"] != "3" {
+		t.Errorf("unexpected metadata: %+v", out)
+	}
+	if p := out.Positions["products"]; string(p.Cursor) != "\x01\x02\x03" || p.Skip != 2 {
+		t.Errorf("unexpected products position: %+v", p)
+	}
+	if p, ok := out.Positions["orders"]; !ok || p.Cursor != nil {
+		t.Errorf("expected empty orders position, got %+v (present: %v)", p, ok)
+	}
+}
+
+func TestDecodeSkipsUnknownRecords(t *testing.T) {
+	c := Encode(Composite{Events: 7})
+	// A record type from a future version
+	c = append(c, 99, 3, 'a', 'b', 'c')
+
+	out, err := Decode(c)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.Events != 7 {
+		t.Errorf("expected events 7, got %d", out.Events)
+	}
+}
+
+func TestDecodeVersion1(t *testing.T) {
+	v1 := es.Cursor("ESM\x01\x01\x08products\x02\x01\x09")
+	out, err := Decode(v1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p := out.Positions["products"]; out.Version != 1 || string(p.Cursor) != "\x09" || p.Skip != 2 {
+		t.Errorf("unexpected v1 decoding: %+v", out)
+	}
+}
+
+func TestDecodeRejectsInvalid(t *testing.T) {
+	valid := Encode(Composite{Positions: map[string]Position{"a": {Cursor: es.Cursor("x")}}})
+	for _, bad := range []es.Cursor{
+		es.Cursor("nope"),
+		es.Cursor("ESM\x07"),
+		valid[:len(valid)-2],
+		es.Cursor("ESM\x01\x05"),
+	} {
+		if _, err := Decode(bad); !errors.Is(err, ErrInvalid) {
+			t.Errorf("expected ErrInvalid for %x, got %v", []byte(bad), err)
+		}
+	}
+
+	// Counts that do not fit the decoded types
+	huge := binary.AppendUvarint(nil, 1<<63)
+	position := append([]byte{1, 'a'}, huge...)
+	for _, bad := range []es.Cursor{
+		append(append(es.Cursor("ESM\x02\x01"), byte(len(position))), position...),
+		append(append(es.Cursor("ESM\x02\x03"), byte(len(huge))), huge...),
+		append(append(es.Cursor("ESM\x01\x01\x01a"), huge...), 0),
+	} {
+		if _, err := Decode(bad); !errors.Is(err, ErrInvalid) {
+			t.Errorf("expected ErrInvalid for out-of-range %x, got %v", []byte(bad), err)
+		}
+	}
+
+	if out, err := Decode(nil); err != nil || len(out.Positions) != 0 {
+		t.Errorf("expected empty composite for empty cursor, got %+v, %v", out, err)
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	c := Encode(Composite{
+		Positions: map[string]Position{
+			"products": {Cursor: es.Cursor("offset-7"), Skip: 2},
+			"orders":   {Cursor: es.Cursor{0x05, 0x00}},
+		},
+		Timestamp: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		Events:    42,
+	})
+
+	want := `composite v2 at=2024-05-01T12:00:00Z events=42 orders=0x0500 products="offset-7"+2`
+	if got := Describe(c); got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+	if got := Describe(nil); got != "<start>" {
+		t.Errorf("expected <start>, got %s", got)
+	}
+	if got := Describe(es.Cursor("ESM\x09")); got != "0x45534d09" {
+		t.Errorf("expected an unreadable composite cursor as raw hex, got %s", got)
+	}
+}
+
+func TestRawCursorWithCompositePrefix(t *testing.T) {
+	// Postgres row id 38622021 in little-endian starts with "ESM" and a version byte
+	raw := binary.LittleEndian.AppendUint64(nil, 0x024d5345)
+	if IsComposite(raw) {
+		t.Errorf("expected %x not to be a composite cursor", raw)
+	}
+	if got := Describe(raw); got != "0x45534d0200000000" {
+		t.Errorf("expected the row id as raw hex, got %s", got)
+	}
+	if !IsComposite(Encode(Composite{})) {
+		t.Error("expected an encoded composite to be recognised")
+	}
+}
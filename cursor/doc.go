@@ -0,0 +1,11 @@
+// Package cursor encodes composite cursors: several named sub-cursors plus metadata
+// packed into one es.Cursor, for sources that track more than one position (merged
+// consumers, shards, reorder buffers).
+//
+// The encoding is versioned and self-describing. Version 2, the one written by Encode,
+// is a sequence of typed, length-prefixed records; decoders skip record types they do
+// not know, so new fields can be added without breaking older readers. Version 1 is the
+// fixed layout written by the first merge.Consumer and is still decoded.
+//
+// Describe renders any cursor, composite or not, for logs and checkpoint tooling.
+package cursor
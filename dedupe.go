@@ -0,0 +1,158 @@
+package projector
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	es "github.com/shogotsuneto/go-simple-eventstore"
+)
+
+// Deduper gives effectively-once semantics on top of at-least-once delivery by
+// remembering which events have already been applied, keyed by event ID.
+// Events without an ID are never filtered.
+//
+// Worker calls Filter before Apply and Mark after Apply succeeds. For projections
+// in a SQL database, prefer recording applied IDs in the same transaction as the
+// projection (see the postgres subpackage's Deduper) so a crash cannot separate the two.
+type Deduper interface {
+	// Filter returns the events of batch that have not been applied yet, in order.
+	Filter(ctx context.Context, batch []es.Envelope) ([]es.Envelope, error)
+	// Mark records events as applied.
+	Mark(ctx context.Context, batch []es.Envelope) error
+}
+
+// Compile-time interface compliance check
+var _ Deduper = (*MemoryDeduper)(nil)
+
+// MemoryDeduper is an in-memory Deduper that keeps the most recently applied event IDs.
+//
+// It bounds memory by Capacity (least recently marked IDs are evicted first) and by TTL
+// (IDs older than TTL are forgotten). Because it is lost on restart, it only protects
+// against duplicates delivered within one process lifetime, which covers redeliveries
+// caused by source retries and rewinds.
+type MemoryDeduper struct {
+	Capacity int           // default: 100000 IDs
+	TTL      time.Duration // optional; 0 keeps IDs until evicted by Capacity
+	Clock    Clock         // optional; defaults to the real clock
+
+	mu    sync.Mutex
+	order *list.List               // front = most recently marked
+	ids   map[string]*list.Element // value: dedupeEntry
+}
+
+// dedupeEntry is an applied event ID and when it was marked
+type dedupeEntry struct {
+	id string
+	at time.Time
+}
+
+// Filter implements Deduper. It also drops repeated IDs within batch.
+func (d *MemoryDeduper) Filter(ctx context.Context, batch []es.Envelope) ([]es.Envelope, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.now()
+	seen := make(map[string]bool, len(batch))
+	out := make([]es.Envelope, 0, len(batch))
+	for _, ev := range batch {
+		id := ev.Event.ID
+		if id != "" {
+			if seen[id] || d.contains(id, now) {
+				continue
+			}
+			seen[id] = true
+		}
+		out = append(out, ev)
+	}
+	return out, nil
+}
+
+// Mark implements Deduper.
+func (d *MemoryDeduper) Mark(ctx context.Context, batch []es.Envelope) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.ids == nil {
+		d.ids = make(map[string]*list.Element)
+		d.order = list.New()
+	}
+
+	capacity := d.Capacity
+	if capacity <= 0 {
+		capacity = 100000
+	}
+
+	now := d.now()
+	for _, ev := range batch {
+		id := ev.Event.ID
+		if id == "" {
+			continue
+		}
+		if el, ok := d.ids[id]; ok {
+			el.Value = dedupeEntry{id: id, at: now}
+			d.order.MoveToFront(el)
+			continue
+		}
+		d.ids[id] = d.order.PushFront(dedupeEntry{id: id, at: now})
+	}
+
+	for d.order.Len() > capacity {
+		d.removeElement(d.order.Back())
+	}
+	return nil
+}
+
+// Prune forgets IDs older than TTL and returns how many were removed.
+// Expired IDs are also ignored by Filter, so calling Prune only reclaims memory.
+func (d *MemoryDeduper) Prune() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.TTL <= 0 || d.order == nil {
+		return 0
+	}
+
+	cutoff := d.now().Add(-d.TTL)
+	removed := 0
+	for el := d.order.Back(); el != nil && el.Value.(dedupeEntry).at.Before(cutoff); el = d.order.Back() {
+		d.removeElement(el)
+		removed++
+	}
+	return removed
+}
+
+// Len returns the number of remembered IDs.
+func (d *MemoryDeduper) Len() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.ids)
+}
+
+// contains reports whether id was marked and has not expired; d.mu must be held
+func (d *MemoryDeduper) contains(id string, now time.Time) bool {
+	el, ok := d.ids[id]
+	if !ok {
+		return false
+	}
+	if d.TTL > 0 && now.Sub(el.Value.(dedupeEntry).at) > d.TTL {
+		d.removeElement(el)
+		return false
+	}
+	return true
+}
+
+// removeElement drops an entry; d.mu must be held
+func (d *MemoryDeduper) removeElement(el *list.Element) {
+	delete(d.ids, el.Value.(dedupeEntry).id)
+	d.order.Remove(el)
+}
+
+// now reads the configured clock
+func (d *MemoryDeduper) now() time.Time {
+	if d.Clock != nil {
+		return d.Clock.Now()
+	}
+	return time.Now()
+}
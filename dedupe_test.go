@@ -0,0 +1,133 @@
+package projector
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	es "github.com/shogotsuneto/go-simple-eventstore"
+)
+
+// stubClock is a Clock whose Now is set by the test
+type stubClock struct{ now time.Time }
+
+func (c *stubClock) Now() time.Time                         { return c.now }
+func (c *stubClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+func (c *stubClock) NewTimer(d time.Duration) Timer         { return realTimer{time.NewTimer(d)} }
+
+func eventIDsOf(batch []es.Envelope) []string {
+	ids := make([]string, len(batch))
+	for i, ev := range batch {
+		ids[i] = ev.Event.ID
+	}
+	return ids
+}
+
+func TestMemoryDeduperFiltersMarkedEvents(t *testing.T) {
+	d := &MemoryDeduper{}
+	ctx := context.Background()
+
+	if err := d.Mark(ctx, []es.Envelope{createTestEvent("1", "a"), createTestEvent("2", "b")}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	batch := []es.Envelope{
+		createTestEvent("2", "b"),
+		createTestEvent("3", "c"),
+		createTestEvent("3", "c"), // repeated within the batch
+		createTestEvent("", "no-id"),
+	}
+	got, err := d.Filter(ctx, batch)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ids := eventIDsOf(got)
+	if len(ids) != 2 || ids[0] != "3" || ids[1] != "" {
+		t.Errorf("expected events [3, <no id>], got %q", ids)
+	}
+}
+
+func TestMemoryDeduperCapacityEvictsOldest(t *testing.T) {
+	d := &MemoryDeduper{Capacity: 2}
+	ctx := context.Background()
+
+	_ = d.Mark(ctx, []es.Envelope{createTestEvent("1", "a")})
+	_ = d.Mark(ctx, []es.Envelope{createTestEvent("2", "a")})
+	_ = d.Mark(ctx, []es.Envelope{createTestEvent("3", "a")})
+
+	if d.Len() != 2 {
+		t.Errorf("expected 2 remembered IDs, got %d", d.Len())
+	}
+
+	got, _ := d.Filter(ctx, []es.Envelope{createTestEvent("1", "a"), createTestEvent("3", "a")})
+	if ids := eventIDsOf(got); len(ids) != 1 || ids[0] != "1" {
+		t.Errorf("expected evicted event 1 to pass, got %q", ids)
+	}
+}
+
+func TestMemoryDeduperTTL(t *testing.T) {
+	clock := &stubClock{now: time.Unix(0, 0)}
+	d := &MemoryDeduper{TTL: time.Minute, Clock: clock}
+	ctx := context.Background()
+
+	_ = d.Mark(ctx, []es.Envelope{createTestEvent("1", "a")})
+	clock.now = clock.now.Add(30 * time.Second)
+	_ = d.Mark(ctx, []es.Envelope{createTestEvent("2", "a")})
+
+	clock.now = clock.now.Add(45 * time.Second)
+
+	got, _ := d.Filter(ctx, []es.Envelope{createTestEvent("1", "a"), createTestEvent("2", "a")})
+	if ids := eventIDsOf(got); len(ids) != 1 || ids[0] != "1" {
+		t.Errorf("expected expired event 1 to pass, got %q", ids)
+	}
+
+	clock.now = clock.now.Add(time.Minute)
+	if removed := d.Prune(); removed != 1 {
+		t.Errorf("expected Prune to remove 1 ID, got %d", removed)
+	}
+	if d.Len() != 0 {
+		t.Errorf("expected no remembered IDs, got %d", d.Len())
+	}
+}
+
+func TestWorkerDeduperDropsRedeliveredEvents(t *testing.T) {
+	consumer := newFakeConsumer()
+	consumer.AddBatch([]es.Envelope{createTestEvent("1", "a"), createTestEvent("2", "b")}, es.Cursor("cursor1"))
+	consumer.AddBatch([]es.Envelope{createTestEvent("2", "b"), createTestEvent("3", "c")}, es.Cursor("cursor2"))
+	consumer.AddBatch([]es.Envelope{createTestEvent("3", "c")}, es.Cursor("cursor3"))
+
+	applied := []appliedBatch{}
+	worker := &Worker{
+		Source:    consumer,
+		Start:     es.Cursor("start"),
+		Deduper:   &MemoryDeduper{},
+		IdleSleep: time.Millisecond,
+		Apply: func(ctx context.Context, batch []es.Envelope, next es.Cursor) error {
+			applied = append(applied, appliedBatch{batch: batch, cursor: next})
+			return nil
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := worker.Run(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+
+	if len(applied) != 3 {
+		t.Fatalf("expected 3 apply calls, got %d", len(applied))
+	}
+	want := [][]string{{"1", "2"}, {"3"}, {}}
+	for i, w := range want {
+		if got := eventIDsOf(applied[i].batch); fmt.Sprint(got) != fmt.Sprint(w) {
+			t.Errorf("apply %d: expected %q, got %q", i, w, got)
+		}
+	}
+
+	// A fully duplicate batch still advances the checkpoint
+	if string(applied[2].cursor) != "cursor3" {
+		t.Errorf("expected last apply with cursor3, got %q", applied[2].cursor)
+	}
+}
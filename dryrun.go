@@ -0,0 +1,164 @@
+package projector
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	es "github.com/shogotsuneto/go-simple-eventstore"
+)
+
+// DryRunReport summarizes a Worker.DryRun.
+type DryRunReport struct {
+	Start        es.Cursor      // cursor the dry run started from (Worker.Start)
+	End          es.Cursor      // cursor the worker would have committed last
+	Batches      int            // batches passed to Apply
+	Events       int            // events passed to Apply, after Filter and Deduper
+	Filtered     int            // events dropped by Filter
+	EventsByType map[string]int // events passed to Apply, by type
+	HandlersHit  map[string]int // HandlerHit calls, by handler name
+	Errors       []*Error       // failed batches (errors and panics in Apply); the dry run continues past them
+	Duration     time.Duration
+}
+
+// String renders the report as a multi-line summary.
+func (r *DryRunReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "dry run: %d events in %d batches (%d filtered, %d failed batches) in %s\n",
+		r.Events, r.Batches, r.Filtered, len(r.Errors), r.Duration.Round(time.Millisecond))
+	fmt.Fprintf(&b, "cursor: %x -> %x\n", []byte(r.Start), []byte(r.End))
+	for _, t := range sortedCounts(r.EventsByType) {
+		fmt.Fprintf(&b, "  event %s: %d\n", t, r.EventsByType[t])
+	}
+	for _, h := range sortedCounts(r.HandlersHit) {
+		fmt.Fprintf(&b, "  handler %s: %d\n", h, r.HandlersHit[h])
+	}
+	for _, err := range r.Errors {
+		fmt.Fprintf(&b, "  error: %v\n", err)
+	}
+	return b.String()
+}
+
+func sortedCounts(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// dryRunKey is the context key under which DryRun passes its recorder to Apply
+type dryRunKey struct{}
+
+// dryRun collects HandlerHit calls and failed batches; Apply may run handlers concurrently
+type dryRun struct {
+	mu     sync.Mutex
+	report *DryRunReport
+}
+
+// IsDryRun reports whether ctx belongs to Apply during Worker.DryRun. postgres.InTx
+// rolls back instead of committing then; other Apply functions must check it and
+// skip or roll back their writes.
+func IsDryRun(ctx context.Context) bool {
+	_, ok := ctx.Value(dryRunKey{}).(*dryRun)
+	return ok
+}
+
+// HandlerHit records that the projection's handler name ran, for
+// DryRunReport.HandlersHit. Outside a dry run it does nothing, so handlers can call it
+// unconditionally.
+func HandlerHit(ctx context.Context, name string) {
+	d, ok := ctx.Value(dryRunKey{}).(*dryRun)
+	if !ok {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.report.HandlersHit[name]++
+}
+
+// DryRun runs the worker from Start until it is caught up without persisting anything,
+// to preview what a changed projection would do. Apply (with Middleware) runs against
+// real events under a context for which IsDryRun is true, Source.Commit is never called
+// and Deduper only filters. Apply errors and panics are recorded in the report instead
+// of stopping the run.
+//
+// The worker's own state is left alone: Validator, Reorder, Breaker, BatchSizer,
+// RateLimit, Idempotency, Hooks and Notifier are not used, and Status is not updated.
+// Only fetch failures and ctx end the dry run early, returning the partial report.
+func (w *Worker) DryRun(ctx context.Context) (*DryRunReport, error) {
+	report := &DryRunReport{
+		Start:        w.Start,
+		End:          w.Start,
+		EventsByType: map[string]int{},
+		HandlersHit:  map[string]int{},
+	}
+	d := &dryRun{report: report}
+	apply := Chain(w.Middleware...)(w.Apply)
+
+	dry := &Worker{
+		Source:    readOnlySource{w.Source},
+		Start:     w.Start,
+		BatchSize: w.BatchSize,
+		Filter:    w.Filter,
+		StopAtEnd: true,
+		Logger:    w.Logger,
+		Clock:     w.Clock,
+		Apply: func(ctx context.Context, batch []es.Envelope, next es.Cursor) error {
+			ctx = context.WithValue(ctx, dryRunKey{}, d)
+			committed, _ := CommittedCursor(ctx)
+
+			err := func() (err error) {
+				defer recoverPanic(PhaseApply, committed, next, batch, &err)
+				return apply(ctx, batch, next)
+			}()
+
+			d.mu.Lock()
+			defer d.mu.Unlock()
+			for _, ev := range batch {
+				report.EventsByType[ev.Event.Type]++
+			}
+			if err != nil {
+				w.logf("dry run apply error", "error", err, "eventCount", len(batch))
+				report.Errors = append(report.Errors, newError(PhaseApply, committed, next, batch, err))
+			}
+			return nil
+		},
+	}
+	if w.Deduper != nil {
+		dry.Deduper = filterOnly{w.Deduper}
+	}
+
+	started := dry.clock().Now()
+	err := dry.Run(ctx)
+
+	st := dry.Status()
+	report.End = st.Cursor
+	report.Batches = int(st.Batches)
+	report.Events = int(st.Events)
+	report.Filtered = int(st.Filtered)
+	report.Duration = dry.clock().Now().Sub(started)
+	return report, err
+}
+
+// readOnlySource never commits, so a dry run does not move the source's position
+type readOnlySource struct {
+	es.Consumer
+}
+
+func (readOnlySource) Commit(ctx context.Context, next es.Cursor) error {
+	return nil
+}
+
+// filterOnly drops already-applied events without marking new ones
+type filterOnly struct {
+	Deduper
+}
+
+func (filterOnly) Mark(ctx context.Context, batch []es.Envelope) error {
+	return nil
+}
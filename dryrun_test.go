@@ -0,0 +1,101 @@
+package projector
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	es "github.com/shogotsuneto/go-simple-eventstore"
+)
+
+func TestWorkerDryRun(t *testing.T) {
+	consumer := newFakeConsumer()
+	consumer.AddBatch([]es.Envelope{
+		filterEvent("1", "product-1", "product.tag_added", nil),
+		filterEvent("2", "product-1", "product.renamed", nil),
+	}, es.Cursor("cursor1"))
+	consumer.AddBatch([]es.Envelope{
+		filterEvent("3", "product-1", "product.tag_added", nil),
+	}, es.Cursor("cursor2"))
+	consumer.AddBatch([]es.Envelope{
+		filterEvent("4", "product-1", "product.tag_removed", nil),
+	}, es.Cursor("cursor3"))
+
+	deduper := &MemoryDeduper{}
+	_ = deduper.Mark(context.Background(), []es.Envelope{filterEvent("2", "product-1", "product.renamed", nil)})
+
+	errBoom := errors.New("boom")
+	worker := &Worker{
+		Source:  consumer,
+		Start:   es.Cursor("start"),
+		Filter:  TypeMatches("product.tag_*"),
+		Deduper: deduper,
+		Apply: func(ctx context.Context, batch []es.Envelope, next es.Cursor) error {
+			if !IsDryRun(ctx) {
+				t.Error("expected IsDryRun inside Apply")
+			}
+			for _, ev := range batch {
+				switch ev.Event.Type {
+				case "product.tag_added":
+					HandlerHit(ctx, "TagAdded")
+				case "product.tag_removed":
+					panic("unhandled removal")
+				}
+			}
+			if string(next) == "cursor2" {
+				return errBoom
+			}
+			return nil
+		},
+	}
+
+	report, err := worker.DryRun(context.Background())
+	if err != nil {
+		t.Fatalf("expected dry run to finish once caught up, got %v", err)
+	}
+
+	if report.Batches != 3 || report.Events != 3 || string(report.End) != "cursor3" {
+		t.Errorf("expected 3 batches with 3 events up to cursor3, got %+v", report)
+	}
+	if report.EventsByType["product.tag_added"] != 2 || report.EventsByType["product.renamed"] != 0 {
+		t.Errorf("expected only deduplicated, unfiltered events by type, got %v", report.EventsByType)
+	}
+	if report.HandlersHit["TagAdded"] != 2 {
+		t.Errorf("expected 2 TagAdded hits, got %v", report.HandlersHit)
+	}
+
+	if len(report.Errors) != 2 {
+		t.Fatalf("expected 2 failed batches, got %v", report.Errors)
+	}
+	if !errors.Is(report.Errors[0], errBoom) || string(report.Errors[0].Cursor) != "cursor1" {
+		t.Errorf("expected the apply error at committed cursor1, got %v", report.Errors[0])
+	}
+	var perr *PanicError
+	if !errors.As(report.Errors[1], &perr) || perr.Value != "unhandled removal" {
+		t.Errorf("expected the panic to be recorded, got %v", report.Errors[1])
+	}
+
+	// Nothing was persisted: no source commits, no dedupe marks, no worker status
+	if len(consumer.commitCalls) != 0 {
+		t.Errorf("expected no commits, got %q", consumer.commitCalls)
+	}
+	if deduper.Len() != 1 {
+		t.Errorf("expected dry run not to mark events, got %d marked", deduper.Len())
+	}
+	if st := worker.Status(); st.Batches != 0 || st.Cursor != nil {
+		t.Errorf("expected worker status untouched, got %+v", st)
+	}
+
+	if s := report.String(); !strings.Contains(s, "event product.tag_added: 2") || !strings.Contains(s, "handler TagAdded: 2") {
+		t.Errorf("unexpected summary:\n%s", s)
+	}
+}
+
+func TestHandlerHitOutsideDryRun(t *testing.T) {
+	ctx := context.Background()
+	HandlerHit(ctx, "TagAdded") // must not panic
+	if IsDryRun(ctx) {
+		t.Error("expected IsDryRun to be false outside a dry run")
+	}
+}
@@ -0,0 +1,83 @@
+package projector
+
+import (
+	"fmt"
+	"runtime/debug"
+
+	es "github.com/shogotsuneto/go-simple-eventstore"
+)
+
+// Phase identifies the step of the worker loop an error or panic happened in.
+type Phase string
+
+const (
+	PhaseFetch    Phase = "fetch"
+	PhaseDedupe   Phase = "dedupe"   // Deduper.Filter or Deduper.Mark
+	PhaseReorder  Phase = "reorder"  // ReorderBuffer, including OnTimeout
+	PhaseValidate Phase = "validate" // StreamValidator, including loading/saving versions
+	PhaseApply    Phase = "apply"
+	PhaseVerify   Phase = "verify" // IdempotencyCheck
+	PhaseCommit   Phase = "commit"
+)
+
+// Error is returned by Run when a step of the worker loop fails. It wraps the cause,
+// so errors.Is and errors.As see through it. Context cancellation noticed between
+// batches is returned as the plain ctx.Err().
+type Error struct {
+	Phase    Phase
+	Cursor   es.Cursor // fetch position (Fetch) or last committed cursor (later phases)
+	Next     es.Cursor // cursor of the batch being processed; nil for Fetch
+	EventIDs []string  // IDs of the events in the batch
+	Count    int       // number of events in the batch
+	Err      error     // underlying cause
+}
+
+func (e *Error) Error() string {
+	if e.Phase == PhaseFetch {
+		return fmt.Sprintf("projector: fetch at cursor %x: %v", e.Cursor, e.Err)
+	}
+	return fmt.Sprintf("projector: %s at cursor %x (next %x, %d events): %v", e.Phase, e.Cursor, e.Next, e.Count, e.Err)
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+func newError(phase Phase, cursor, next es.Cursor, batch []es.Envelope, err error) *Error {
+	return &Error{Phase: phase, Cursor: cursor, Next: next, EventIDs: eventIDs(batch), Count: len(batch), Err: err}
+}
+
+// PanicError is the cause of the *Error returned by Run when Source.Fetch, Apply or
+// Source.Commit panics. It carries the stack trace of the panic.
+type PanicError struct {
+	Phase    Phase
+	Cursor   es.Cursor // fetch position (Fetch) or last committed cursor (Apply, Commit)
+	Next     es.Cursor // cursor of the batch being applied/committed; nil for Fetch
+	EventIDs []string  // IDs of the events in the batch; nil for Fetch
+	Value    any       // value passed to panic
+	Stack    []byte    // stack trace of the panicking goroutine
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("projector: panic in %s at cursor %x (%d events): %v", e.Phase, e.Cursor, len(e.EventIDs), e.Value)
+}
+
+// recoverPanic turns a panic into a *PanicError stored in *err. It must be deferred directly.
+func recoverPanic(phase Phase, cursor, next es.Cursor, batch []es.Envelope, err *error) {
+	v := recover()
+	if v == nil {
+		return
+	}
+
+	*err = &PanicError{Phase: phase, Cursor: cursor, Next: next, EventIDs: eventIDs(batch), Value: v, Stack: debug.Stack()}
+}
+
+// eventIDs returns the IDs of the events in batch, or nil for an empty batch
+func eventIDs(batch []es.Envelope) []string {
+	if len(batch) == 0 {
+		return nil
+	}
+	ids := make([]string, len(batch))
+	for i, ev := range batch {
+		ids[i] = ev.Event.ID
+	}
+	return ids
+}
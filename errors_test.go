@@ -0,0 +1,133 @@
+package projector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	es "github.com/shogotsuneto/go-simple-eventstore"
+)
+
+// panicConsumer panics in the configured phase and delegates to fakeConsumer otherwise
+type panicConsumer struct {
+	*fakeConsumer
+	phase Phase
+}
+
+func (p *panicConsumer) Fetch(ctx context.Context, cursor es.Cursor, limit int) ([]es.Envelope, es.Cursor, error) {
+	if p.phase == PhaseFetch {
+		panic("fetch boom")
+	}
+	return p.fakeConsumer.Fetch(ctx, cursor, limit)
+}
+
+func (p *panicConsumer) Commit(ctx context.Context, cursor es.Cursor) error {
+	if p.phase == PhaseCommit {
+		panic("commit boom")
+	}
+	return p.fakeConsumer.Commit(ctx, cursor)
+}
+
+func TestWorkerRecoversPanics(t *testing.T) {
+	for _, phase := range []Phase{PhaseFetch, PhaseApply, PhaseCommit} {
+		t.Run(string(phase), func(t *testing.T) {
+			consumer := newFakeConsumer()
+			consumer.AddBatch([]es.Envelope{createTestEvent("1", "e"), createTestEvent("2", "e")}, es.Cursor("cursor1"))
+
+			worker := &Worker{
+				Source:    &panicConsumer{fakeConsumer: consumer, phase: phase},
+				Start:     es.Cursor("start"),
+				IdleSleep: time.Millisecond,
+				Apply: func(ctx context.Context, batch []es.Envelope, next es.Cursor) error {
+					if phase == PhaseApply {
+						panic(fmt.Errorf("apply boom"))
+					}
+					return nil
+				},
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			err := worker.Run(ctx)
+
+			var perr *PanicError
+			if !errors.As(err, &perr) {
+				t.Fatalf("expected *PanicError, got %v", err)
+			}
+			if perr.Phase != phase || string(perr.Cursor) != "start" || len(perr.Stack) == 0 {
+				t.Errorf("unexpected panic error: %+v", perr)
+			}
+			if !strings.Contains(err.Error(), "boom") {
+				t.Errorf("expected panic value in message, got %q", err.Error())
+			}
+
+			wantIDs, wantNext := "[1 2]", "cursor1"
+			if phase == PhaseFetch {
+				wantIDs, wantNext = "[]", ""
+			}
+			if got := fmt.Sprint(perr.EventIDs); got != wantIDs || string(perr.Next) != wantNext {
+				t.Errorf("expected events %s / next %q, got %s / %q", wantIDs, wantNext, got, perr.Next)
+			}
+			if worker.Status().Running {
+				t.Error("expected worker to be stopped")
+			}
+		})
+	}
+}
+
+func TestWorkerErrorIdentifiesPhaseAndBatch(t *testing.T) {
+	consumer := newFakeConsumer()
+	consumer.AddBatch([]es.Envelope{createTestEvent("1", "e")}, es.Cursor("cursor1"))
+	consumer.AddBatch([]es.Envelope{createTestEvent("2", "e"), createTestEvent("3", "e")}, es.Cursor("cursor2"))
+
+	applyErr := errors.New("apply failed")
+	worker := &Worker{
+		Source:    consumer,
+		Start:     es.Cursor("start"),
+		IdleSleep: time.Millisecond,
+		Apply: func(ctx context.Context, batch []es.Envelope, next es.Cursor) error {
+			if string(next) == "cursor2" {
+				return applyErr
+			}
+			return nil
+		},
+	}
+
+	err := worker.Run(context.Background())
+
+	var werr *Error
+	if !errors.As(err, &werr) {
+		t.Fatalf("expected *Error, got %v", err)
+	}
+	if !errors.Is(err, applyErr) {
+		t.Errorf("expected error to wrap the apply error, got %v", err)
+	}
+	if werr.Phase != PhaseApply || string(werr.Cursor) != "cursor1" || string(werr.Next) != "cursor2" {
+		t.Errorf("unexpected phase/cursors: %s %q %q", werr.Phase, werr.Cursor, werr.Next)
+	}
+	if got := fmt.Sprint(werr.EventIDs); got != "[2 3]" || werr.Count != 2 {
+		t.Errorf("expected events [2 3], got %s (count %d)", got, werr.Count)
+	}
+
+	want := `projector: apply at cursor 637572736f7231 (next 637572736f7232, 2 events): apply failed`
+	if err.Error() != want {
+		t.Errorf("expected message %q, got %q", want, err.Error())
+	}
+}
+
+func TestWorkerContextCancellationIsNotWrapped(t *testing.T) {
+	worker := &Worker{
+		Source:    newFakeConsumer(),
+		IdleSleep: time.Millisecond,
+		Apply:     func(ctx context.Context, batch []es.Envelope, next es.Cursor) error { return nil },
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := worker.Run(ctx); err != context.DeadlineExceeded {
+		t.Errorf("expected bare context.DeadlineExceeded, got %v", err)
+	}
+}
@@ -6,6 +6,9 @@
 // - Running the projector with user-defined Apply function
 // - Atomic projection + checkpoint persistence using database transactions
 // - Restarting from the saved cursor without re-applying past events
+// - Restarting the worker with backoff after failures via a Supervisor
+// - Waking up on new events via Postgres LISTEN/NOTIFY instead of tight polling
+// - Previewing a projection change with a dry run (DRY_RUN=1) that persists nothing
 // - Projecting product tag events to enable product search by tags
 package main
 
@@ -13,6 +16,7 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -21,6 +25,7 @@ import (
 
 	_ "github.com/lib/pq" // PostgreSQL driver
 	"github.com/shogotsuneto/go-simple-es-projector"
+	pgprojector "github.com/shogotsuneto/go-simple-es-projector/postgres"
 	es "github.com/shogotsuneto/go-simple-eventstore"
 	"github.com/shogotsuneto/go-simple-eventstore/postgres"
 )
@@ -88,7 +93,13 @@ func main() {
 		log.Fatalf("Failed to create event consumer: %v", err)
 	}
 
-	// Load starting cursor from our checkpoint table
+	// Wake up as soon as the producer appends events (trigger in init-eventstore.sql)
+	notifier, err := pgprojector.NewNotifier(eventstoreURL, pgprojector.DefaultChannel)
+	if err != nil {
+		log.Fatalf("Failed to create notifier: %v", err)
+	}
+	defer notifier.Close()
+
 	ctx := context.Background()
 
 	// Apply timeout if specified
@@ -98,25 +109,32 @@ func main() {
 		defer cancel()
 	}
 
-	cursor, err := loadCursor(ctx, projectionDB)
-	if err != nil {
-		log.Fatalf("Failed to load cursor: %v", err)
-	}
-
-	log.Printf("Starting projection from cursor: %s", cursor)
-
-	// Create and configure the worker
+	// Create and configure the worker; the supervisor loads its starting cursor
 	worker := &projector.Worker{
 		Source:    src,
-		Start:     cursor,
-		BatchSize: 10, // Small batches for demo
-		IdleSleep: 2 * time.Second,
+		BatchSize: 10,              // Small batches for demo
+		IdleSleep: 5 * time.Second, // Fallback polling; notifications wake the worker earlier
+		Notifier:  notifier,
+		Filter:    projector.TypeMatches("product.tag_*"), // the only events this projection handles
 		Apply:     createApplyFunc(projectionDB),
 		Logger: func(msg string, kv ...any) {
 			log.Printf("[WORKER] %s %v", msg, kv)
 		},
 	}
 
+	// Preview what the projection would do from its checkpoint, then exit
+	if os.Getenv("DRY_RUN") != "" {
+		if worker.Start, err = loadCursor(ctx, projectionDB); err != nil {
+			log.Fatalf("Failed to load cursor: %v", err)
+		}
+		report, err := worker.DryRun(ctx)
+		if err != nil {
+			log.Fatalf("Dry run failed: %v", err)
+		}
+		log.Printf("%s", report)
+		return
+	}
+
 	// Run the projector
 	if timeout > 0 {
 		log.Printf("Starting projector with %v timeout...", timeout)
@@ -124,8 +142,19 @@ func main() {
 		log.Println("Starting projector...")
 	}
 
-	err = worker.Run(ctx)
-	if err == context.DeadlineExceeded {
+	// Restart the worker from its checkpoint when it fails (e.g. projection DB restarts)
+	supervisor := &projector.Supervisor{
+		Workers: map[string]*projector.Worker{"product_tags": worker},
+		Checkpoints: projector.CheckpointFunc(func(ctx context.Context, name string) (es.Cursor, error) {
+			return loadCursor(ctx, projectionDB)
+		}),
+		Logger: func(msg string, kv ...any) {
+			log.Printf("[SUPERVISOR] %s %v", msg, kv)
+		},
+	}
+
+	err = supervisor.Run(ctx)
+	if errors.Is(err, context.DeadlineExceeded) {
 		log.Printf("Projector stopped after %v timeout", timeout)
 	} else if err != nil {
 		log.Fatalf("Projector failed: %v", err)
@@ -226,6 +255,11 @@ func createApplyFunc(db *sql.DB) projector.ApplyFunc {
 			return fmt.Errorf("failed to save cursor: %w", err)
 		}
 
+		// A dry run executes the projection but keeps nothing
+		if projector.IsDryRun(ctx) {
+			return tx.Rollback()
+		}
+
 		// Commit transaction
 		if err := tx.Commit(); err != nil {
 			return fmt.Errorf("failed to commit transaction: %w", err)
@@ -244,6 +278,7 @@ func projectEventTx(ctx context.Context, tx *sql.Tx, envelope es.Envelope) error
 		if err := json.Unmarshal(envelope.Event.Data, &event); err != nil {
 			return fmt.Errorf("failed to unmarshal TagAdded: %w", err)
 		}
+		projector.HandlerHit(ctx, "addProductTag")
 		return addProductTagTx(ctx, tx, event.ProductID, event.Tag, event.UserID)
 
 	case "product.tag_removed":
@@ -251,10 +286,11 @@ func projectEventTx(ctx context.Context, tx *sql.Tx, envelope es.Envelope) error
 		if err := json.Unmarshal(envelope.Event.Data, &event); err != nil {
 			return fmt.Errorf("failed to unmarshal TagRemoved: %w", err)
 		}
+		projector.HandlerHit(ctx, "removeProductTag")
 		return removeProductTagTx(ctx, tx, event.ProductID, event.Tag)
 
 	default:
-		// Unknown event type - skip (be lenient)
+		// Unknown product.tag_* event type - skip (be lenient)
 		log.Printf("Skipping unknown event type: %s", envelope.Event.Type)
 		return nil
 	}
@@ -16,6 +16,19 @@ CREATE INDEX IF NOT EXISTS idx_events_stream_id ON events(stream_id);
 CREATE INDEX IF NOT EXISTS idx_events_stream_version ON events(stream_id, version);
 CREATE INDEX IF NOT EXISTS idx_events_timestamp ON events(timestamp);
 
+-- Notify listening projectors when events are appended (see postgres.TriggerSQL)
+CREATE OR REPLACE FUNCTION events_notify() RETURNS trigger AS $$
+BEGIN
+	PERFORM pg_notify('events_appended', '');
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS events_notify ON events;
+CREATE TRIGGER events_notify
+	AFTER INSERT ON events
+	FOR EACH STATEMENT EXECUTE FUNCTION events_notify();
+
 -- Insert some sample product tag events for demonstration
 INSERT INTO events (stream_id, version, event_id, event_type, event_data, metadata) VALUES
 ('product-123', 1, 'evt-123-1', 'product.tag_added', '{"product_id": "product-123", "tag": "electronics", "user_id": "user-1"}'::bytea, '{"source": "product-service"}'),
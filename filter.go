@@ -0,0 +1,99 @@
+package projector
+
+import (
+	"path"
+	"time"
+
+	es "github.com/shogotsuneto/go-simple-eventstore"
+)
+
+// Filter decides whether an event reaches Apply; return false to drop it.
+// Dropped events are still covered by the cursor, so the worker advances past them.
+type Filter func(ev es.Envelope) bool
+
+// Types keeps events whose type is one of types.
+func Types(types ...string) Filter {
+	set := make(map[string]bool, len(types))
+	for _, t := range types {
+		set[t] = true
+	}
+	return func(ev es.Envelope) bool {
+		return set[ev.Event.Type]
+	}
+}
+
+// TypeMatches keeps events whose type matches the glob pattern (path.Match syntax),
+// e.g. "product.tag_*". A malformed pattern matches nothing.
+func TypeMatches(pattern string) Filter {
+	return func(ev es.Envelope) bool {
+		ok, _ := path.Match(pattern, ev.Event.Type)
+		return ok
+	}
+}
+
+// StreamMatches keeps events whose stream ID matches the glob pattern (path.Match syntax),
+// e.g. "product-*". A malformed pattern matches nothing.
+func StreamMatches(pattern string) Filter {
+	return func(ev es.Envelope) bool {
+		ok, _ := path.Match(pattern, ev.StreamID)
+		return ok
+	}
+}
+
+// MetadataEquals keeps events whose metadata has key set to value.
+func MetadataEquals(key, value string) Filter {
+	return func(ev es.Envelope) bool {
+		v, ok := ev.Event.Metadata[key]
+		return ok && v == value
+	}
+}
+
+// TimeRange keeps events with since <= timestamp < until. A zero bound is open.
+func TimeRange(since, until time.Time) Filter {
+	return func(ev es.Envelope) bool {
+		ts := ev.Event.Timestamp
+		return (since.IsZero() || !ts.Before(since)) && (until.IsZero() || ts.Before(until))
+	}
+}
+
+// All keeps events kept by every filter.
+func All(filters ...Filter) Filter {
+	return func(ev es.Envelope) bool {
+		for _, f := range filters {
+			if !f(ev) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Any keeps events kept by at least one filter.
+func Any(filters ...Filter) Filter {
+	return func(ev es.Envelope) bool {
+		for _, f := range filters {
+			if f(ev) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Not keeps events dropped by f.
+func Not(f Filter) Filter {
+	return func(ev es.Envelope) bool {
+		return !f(ev)
+	}
+}
+
+// apply returns the events f keeps, in order
+func (f Filter) apply(batch []es.Envelope) []es.Envelope {
+	kept := make([]es.Envelope, 0, len(batch))
+	for _, ev := range batch {
+		if f(ev) {
+			kept = append(kept, ev)
+		}
+	}
+	return kept
+}
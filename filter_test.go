@@ -0,0 +1,114 @@
+package projector
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	es "github.com/shogotsuneto/go-simple-eventstore"
+)
+
+func filterEvent(id, streamID, eventType string, metadata map[string]string) es.Envelope {
+	return es.Envelope{StreamID: streamID, Event: es.Event{ID: id, Type: eventType, Metadata: metadata}}
+}
+
+func TestFilterHelpers(t *testing.T) {
+	tagAdded := filterEvent("1", "product-1", "product.tag_added", map[string]string{"source": "product-service"})
+	orderPlaced := filterEvent("2", "order-1", "order.placed", nil)
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   [2]bool // tagAdded, orderPlaced
+	}{
+		{"Types", Types("order.placed", "order.cancelled"), [2]bool{false, true}},
+		{"TypeMatches", TypeMatches("product.tag_*"), [2]bool{true, false}},
+		{"StreamMatches", StreamMatches("order-*"), [2]bool{false, true}},
+		{"MetadataEquals", MetadataEquals("source", "product-service"), [2]bool{true, false}},
+		{"All", All(TypeMatches("product.*"), StreamMatches("product-1")), [2]bool{true, false}},
+		{"Any", Any(Types("order.placed"), MetadataEquals("source", "product-service")), [2]bool{true, true}},
+		{"Not", Not(Types("order.placed")), [2]bool{true, false}},
+		{"MalformedPattern", TypeMatches("["), [2]bool{false, false}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter(tagAdded); got != tt.want[0] {
+				t.Errorf("tag added: expected %v, got %v", tt.want[0], got)
+			}
+			if got := tt.filter(orderPlaced); got != tt.want[1] {
+				t.Errorf("order placed: expected %v, got %v", tt.want[1], got)
+			}
+		})
+	}
+}
+
+func TestTimeRange(t *testing.T) {
+	base := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) es.Envelope {
+		return es.Envelope{Event: es.Event{Timestamp: base.Add(d)}}
+	}
+
+	f := TimeRange(base, base.Add(time.Hour))
+	if !f(at(0)) || !f(at(59*time.Minute)) {
+		t.Error("expected events within the range to be kept")
+	}
+	if f(at(-time.Second)) || f(at(time.Hour)) {
+		t.Error("expected events before since and at until to be dropped")
+	}
+
+	open := TimeRange(time.Time{}, base)
+	if !open(at(-24*time.Hour)) || open(at(0)) {
+		t.Error("expected a zero since to be open")
+	}
+}
+
+func TestWorkerFilterAdvancesPastFilteredBatches(t *testing.T) {
+	consumer := newFakeConsumer()
+	consumer.AddBatch([]es.Envelope{
+		filterEvent("1", "product-1", "product.tag_added", nil),
+		filterEvent("2", "product-1", "product.renamed", nil),
+	}, es.Cursor("cursor1"))
+	consumer.AddBatch([]es.Envelope{
+		filterEvent("3", "product-1", "product.renamed", nil),
+	}, es.Cursor("cursor2"))
+
+	applied := []appliedBatch{}
+	worker := &Worker{
+		Source:    consumer,
+		Start:     es.Cursor("start"),
+		Filter:    TypeMatches("product.tag_*"),
+		IdleSleep: time.Millisecond,
+		Apply: func(ctx context.Context, batch []es.Envelope, next es.Cursor) error {
+			applied = append(applied, appliedBatch{batch: batch, cursor: next})
+			return nil
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := worker.Run(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+
+	if len(applied) != 2 {
+		t.Fatalf("expected 2 apply calls, got %d", len(applied))
+	}
+	if got := fmt.Sprint(eventIDsOf(applied[0].batch)); got != "[1]" {
+		t.Errorf("expected only event 1 applied, got %s", got)
+	}
+
+	// The fully filtered batch still reaches Apply so the checkpoint advances
+	if len(applied[1].batch) != 0 || string(applied[1].cursor) != "cursor2" {
+		t.Errorf("expected empty batch with cursor2, got %d events / %q", len(applied[1].batch), applied[1].cursor)
+	}
+	if got := string(consumer.fetchCalls[2].cursor); got != "cursor2" {
+		t.Errorf("expected fetch to continue from cursor2, got %q", got)
+	}
+
+	status := worker.Status()
+	if status.Filtered != 2 || status.Events != 1 {
+		t.Errorf("expected 2 filtered / 1 applied, got %d / %d", status.Filtered, status.Events)
+	}
+}
@@ -2,4 +2,7 @@ module github.com/shogotsuneto/go-simple-es-projector
 
 go 1.24.6
 
-require github.com/shogotsuneto/go-simple-eventstore v0.0.9
+require (
+	github.com/lib/pq v1.10.9
+	github.com/shogotsuneto/go-simple-eventstore v0.0.9
+)
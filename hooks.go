@@ -0,0 +1,18 @@
+package projector
+
+// Hooks are optional callbacks for observing a Worker (metrics, alerts, tests).
+// Nil fields are ignored. Hooks run synchronously on the worker's goroutine,
+// so keep them fast.
+type Hooks struct {
+	// OnIdempotencyViolation is called when an IdempotencyCheck detects that
+	// redelivering a batch changed the projection.
+	OnIdempotencyViolation func(v IdempotencyViolation)
+
+	// OnStreamAnomaly is called for every gap, duplicate or regression a
+	// StreamValidator detects, before its policy is applied.
+	OnStreamAnomaly func(a StreamAnomaly)
+
+	// OnBreakerStateChange is called when the Worker's CircuitBreaker opens,
+	// goes half-open or closes.
+	OnBreakerStateChange func(t BreakerTransition)
+}
@@ -0,0 +1,114 @@
+package projector
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/rand/v2"
+
+	es "github.com/shogotsuneto/go-simple-eventstore"
+)
+
+// ErrNotIdempotent is returned by Run when an IdempotencyCheck with Halt detects a violation.
+var ErrNotIdempotent = errors.New("projector: apply is not idempotent")
+
+// IdempotencyCheck is a debug/test mode that verifies Apply is idempotent, as the
+// at-least-once delivery contract requires.
+//
+// After a batch is applied, the worker (with probability Probability) fingerprints the
+// projection state, applies the batch again — optionally preceded by the previous batch,
+// as a restart from the previous checkpoint would — and fingerprints again. A different
+// fingerprint means the redelivery changed the projection, e.g. an INSERT without
+// ON CONFLICT or a counter incremented twice. Violations are logged and reported to
+// Hooks.OnIdempotencyViolation.
+//
+// Replays call Apply with cursors the worker already passed, so only enable this against
+// a disposable projection store.
+type IdempotencyCheck struct {
+	// Fingerprint returns a digest of the projection state (e.g. a hash over the read model
+	// and checkpoint tables). Required.
+	Fingerprint func(ctx context.Context) ([]byte, error)
+
+	Probability    float64        // chance of checking a batch; default: 1 (every batch)
+	ReplayPrevious bool           // replay the previous batch before the current one
+	Halt           bool           // make Run return ErrNotIdempotent on a violation
+	Rand           func() float64 // optional; defaults to math/rand/v2
+}
+
+// IdempotencyViolation describes a batch whose redelivery changed the projection state.
+type IdempotencyViolation struct {
+	Next     es.Cursor // cursor of the replayed batch
+	EventIDs []string  // events of the replayed batches, in order
+	Before   []byte    // fingerprint after the first delivery
+	After    []byte    // fingerprint after the redelivery
+}
+
+// delivery is a batch, the cursor it was applied with and the cursor committed before it
+type delivery struct {
+	batch     []es.Envelope
+	next      es.Cursor
+	committed es.Cursor
+}
+
+// sample decides whether this batch gets checked
+func (c *IdempotencyCheck) sample() bool {
+	p := c.Probability
+	if p <= 0 || p >= 1 {
+		return true
+	}
+	rnd := c.Rand
+	if rnd == nil {
+		rnd = rand.Float64
+	}
+	return rnd() < p
+}
+
+// verifyIdempotent redelivers cur (and r.prev, if configured) and compares fingerprints.
+// Replays go through Middleware and panic recovery like the first delivery. It returns
+// an error if Apply or Fingerprint fail, or on a violation when Halt is set.
+func (w *Worker) verifyIdempotent(ctx context.Context, r *runState, cur delivery) error {
+	prev := r.prev
+	check := w.Idempotency
+	if !check.sample() {
+		return nil
+	}
+
+	before, err := check.Fingerprint(ctx)
+	if err != nil {
+		return err
+	}
+
+	replay := []delivery{cur}
+	if check.ReplayPrevious && prev != nil {
+		replay = []delivery{*prev, cur}
+	}
+
+	var ids []string
+	for _, d := range replay {
+		if err := w.applyAt(ctx, r, d.committed, d.batch, d.next); err != nil {
+			return err
+		}
+		for _, ev := range d.batch {
+			ids = append(ids, ev.Event.ID)
+		}
+	}
+
+	after, err := check.Fingerprint(ctx)
+	if err != nil {
+		return err
+	}
+
+	if bytes.Equal(before, after) {
+		return nil
+	}
+
+	v := IdempotencyViolation{Next: cur.next, EventIDs: ids, Before: before, After: after}
+	w.logf("idempotency violation", "eventIDs", ids, "replayedBatches", len(replay))
+	if w.Hooks.OnIdempotencyViolation != nil {
+		w.Hooks.OnIdempotencyViolation(v)
+	}
+	if check.Halt {
+		return ErrNotIdempotent
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package projector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	es "github.com/shogotsuneto/go-simple-eventstore"
+)
+
+// counterProjection counts events; idempotent mode dedupes by event ID
+type counterProjection struct {
+	idempotent bool
+	seen       map[string]bool
+	count      int
+}
+
+func (p *counterProjection) apply(ctx context.Context, batch []es.Envelope, next es.Cursor) error {
+	for _, ev := range batch {
+		if p.idempotent && p.seen[ev.Event.ID] {
+			continue
+		}
+		p.seen[ev.Event.ID] = true
+		p.count++
+	}
+	return nil
+}
+
+func (p *counterProjection) fingerprint(ctx context.Context) ([]byte, error) {
+	return []byte(fmt.Sprint(p.count)), nil
+}
+
+func runIdempotencyCheck(t *testing.T, p *counterProjection, check *IdempotencyCheck) ([]IdempotencyViolation, error) {
+	t.Helper()
+
+	consumer := newFakeConsumer()
+	consumer.AddBatch([]es.Envelope{createTestEvent("1", "a"), createTestEvent("2", "b")}, es.Cursor("cursor1"))
+	consumer.AddBatch([]es.Envelope{createTestEvent("3", "c")}, es.Cursor("cursor2"))
+
+	check.Fingerprint = p.fingerprint
+	var violations []IdempotencyViolation
+	worker := &Worker{
+		Source:      consumer,
+		Start:       es.Cursor("start"),
+		Apply:       p.apply,
+		IdleSleep:   time.Millisecond,
+		Idempotency: check,
+		Hooks: Hooks{OnIdempotencyViolation: func(v IdempotencyViolation) {
+			violations = append(violations, v)
+		}},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	return violations, worker.Run(ctx)
+}
+
+func TestIdempotencyCheckPassesIdempotentApply(t *testing.T) {
+	p := &counterProjection{idempotent: true, seen: map[string]bool{}}
+
+	violations, err := runIdempotencyCheck(t, p, &IdempotencyCheck{ReplayPrevious: true, Halt: true})
+	if err != context.DeadlineExceeded {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if len(violations) != 0 {
+		t.Errorf("expected no violations, got %+v", violations)
+	}
+	if p.count != 3 {
+		t.Errorf("expected 3 events counted, got %d", p.count)
+	}
+}
+
+func TestIdempotencyCheckReportsViolation(t *testing.T) {
+	p := &counterProjection{seen: map[string]bool{}}
+
+	violations, err := runIdempotencyCheck(t, p, &IdempotencyCheck{})
+	if err != context.DeadlineExceeded {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if len(violations) != 2 {
+		t.Fatalf("expected a violation per batch, got %d", len(violations))
+	}
+
+	v := violations[0]
+	if string(v.Next) != "cursor1" || string(v.Before) != "2" || string(v.After) != "4" {
+		t.Errorf("unexpected violation: %+v", v)
+	}
+	if len(v.EventIDs) != 2 || v.EventIDs[0] != "1" || v.EventIDs[1] != "2" {
+		t.Errorf("expected replayed event IDs [1 2], got %v", v.EventIDs)
+	}
+}
+
+func TestIdempotencyCheckReplayPrevious(t *testing.T) {
+	p := &counterProjection{seen: map[string]bool{}}
+
+	violations, _ := runIdempotencyCheck(t, p, &IdempotencyCheck{ReplayPrevious: true})
+	if len(violations) != 2 {
+		t.Fatalf("expected 2 violations, got %d", len(violations))
+	}
+
+	// The second batch is replayed together with the first
+	if ids := violations[1].EventIDs; len(ids) != 3 {
+		t.Errorf("expected previous and current batch replayed, got %v", ids)
+	}
+}
+
+func TestIdempotencyCheckHalt(t *testing.T) {
+	p := &counterProjection{seen: map[string]bool{}}
+
+	_, err := runIdempotencyCheck(t, p, &IdempotencyCheck{Halt: true})
+	if !errors.Is(err, ErrNotIdempotent) {
+		t.Errorf("expected ErrNotIdempotent, got %v", err)
+	}
+}
+
+func TestIdempotencyCheckProbability(t *testing.T) {
+	p := &counterProjection{seen: map[string]bool{}}
+
+	// Rand always above the probability: never sampled
+	violations, _ := runIdempotencyCheck(t, p, &IdempotencyCheck{
+		Probability: 0.1,
+		Rand:        func() float64 { return 0.5 },
+	})
+	if len(violations) != 0 || p.count != 3 {
+		t.Errorf("expected no replays, got %d violations and count %d", len(violations), p.count)
+	}
+}
+
+func TestIdempotencyCheckReplaysThroughMiddlewareAndRecovers(t *testing.T) {
+	consumer := newFakeConsumer()
+	consumer.AddBatch([]es.Envelope{createTestEvent("1", "a")}, es.Cursor("cursor1"))
+
+	deliveries := 0
+	var committed []string
+	worker := &Worker{
+		Source:    consumer,
+		Start:     es.Cursor("start"),
+		IdleSleep: time.Millisecond,
+		Middleware: []Middleware{func(next ApplyFunc) ApplyFunc {
+			return func(ctx context.Context, batch []es.Envelope, cursor es.Cursor) error {
+				c, _ := CommittedCursor(ctx)
+				committed = append(committed, string(c))
+				return next(ctx, batch, cursor)
+			}
+		}},
+		Apply: func(ctx context.Context, batch []es.Envelope, next es.Cursor) error {
+			deliveries++
+			if deliveries == 2 {
+				panic("not safe to redeliver")
+			}
+			return nil
+		},
+		Idempotency: &IdempotencyCheck{Fingerprint: func(ctx context.Context) ([]byte, error) { return nil, nil }},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	err := worker.Run(ctx)
+
+	var werr *Error
+	var perr *PanicError
+	if !errors.As(err, &werr) || werr.Phase != PhaseVerify || !errors.As(err, &perr) {
+		t.Fatalf("expected the replay panic as a verify error, got %v", err)
+	}
+	if fmt.Sprint(committed) != "[start start]" {
+		t.Errorf("expected both deliveries through middleware with committed cursor start, got %v", committed)
+	}
+}
@@ -0,0 +1,125 @@
+// Package source opens the event sources accepted by the commands: a Postgres
+// connection string (go-simple-eventstore's events table) or a JSONL file or directory.
+package source
+
+import (
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/shogotsuneto/go-simple-es-projector/jsonl"
+	es "github.com/shogotsuneto/go-simple-eventstore"
+)
+
+// Counter is implemented by sources that can tell how many events follow a cursor
+// without reading them.
+type Counter interface {
+	Behind(ctx context.Context, cursor es.Cursor) (int64, error)
+}
+
+// Open returns a Postgres consumer for connection strings, else a JSONL consumer, and a
+// function releasing it. Postgres consumers implement Counter.
+func Open(spec, table string) (es.Consumer, func(), error) {
+	if strings.HasPrefix(spec, "postgres://") || strings.HasPrefix(spec, "postgresql://") {
+		if table == "" {
+			return nil, nil, fmt.Errorf("no events table")
+		}
+		db, err := sql.Open("postgres", spec)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := db.Ping(); err != nil {
+			_ = db.Close()
+			return nil, nil, fmt.Errorf("event source: %w", err)
+		}
+		return &pgSource{db: db, table: table}, func() { _ = db.Close() }, nil
+	}
+
+	if _, err := os.Stat(spec); err != nil {
+		return nil, nil, err
+	}
+	src := &jsonl.Consumer{Path: spec}
+	return src, func() { _ = src.Close() }, nil
+}
+
+// pgSource reads go-simple-eventstore's events table like its PostgresEventConsumer,
+// but on a connection pool the caller can close. The cursor is the 8-byte
+// little-endian row id.
+type pgSource struct {
+	db    *sql.DB
+	table string
+}
+
+// Fetch implements es.Consumer.
+func (s *pgSource) Fetch(ctx context.Context, cursor es.Cursor, limit int) ([]es.Envelope, es.Cursor, error) {
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT id, stream_id, event_id, event_type, event_data, metadata, timestamp, version
+		FROM %s
+		WHERE id > $1
+		ORDER BY id ASC
+		LIMIT $2`, pq.QuoteIdentifier(s.table)), rowID(cursor), limit)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query events: %w", err)
+	}
+	defer rows.Close()
+
+	var batch []es.Envelope
+	var last int64
+	for rows.Next() {
+		var env es.Envelope
+		var metadata sql.NullString
+		var ts time.Time
+		err := rows.Scan(&last, &env.StreamID, &env.Event.ID, &env.Event.Type, &env.Event.Data, &metadata, &ts, &env.Event.Version)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to scan event row: %w", err)
+		}
+		if metadata.String != "" {
+			if err := json.Unmarshal([]byte(metadata.String), &env.Event.Metadata); err != nil {
+				return nil, nil, fmt.Errorf("failed to parse metadata: %w", err)
+			}
+		}
+		env.Event.Timestamp = ts
+		env.Partition = s.table
+		env.Offset = strconv.FormatInt(last, 10)
+		batch = append(batch, env)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	if len(batch) == 0 {
+		return nil, cursor, nil
+	}
+	return batch, binary.LittleEndian.AppendUint64(nil, uint64(last)), nil
+}
+
+// Commit implements es.Consumer; like PostgresEventConsumer it is a no-op.
+func (s *pgSource) Commit(ctx context.Context, cursor es.Cursor) error {
+	return nil
+}
+
+// Behind implements Counter as the head row id minus the cursor's. Row ids skipped by
+// rolled-back inserts are counted too, so it is an upper bound.
+func (s *pgSource) Behind(ctx context.Context, cursor es.Cursor) (int64, error) {
+	var head int64
+	query := fmt.Sprintf("SELECT COALESCE(MAX(id), 0) FROM %s", pq.QuoteIdentifier(s.table))
+	if err := s.db.QueryRowContext(ctx, query).Scan(&head); err != nil {
+		return 0, fmt.Errorf("source head: %w", err)
+	}
+	return max(head-rowID(cursor), 0), nil
+}
+
+// rowID decodes a cursor; anything shorter than 8 bytes is the beginning
+func rowID(cursor es.Cursor) int64 {
+	if len(cursor) < 8 {
+		return 0
+	}
+	return int64(binary.LittleEndian.Uint64(cursor[:8]))
+}
@@ -0,0 +1,45 @@
+package source
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/shogotsuneto/go-simple-es-projector/jsonl"
+)
+
+func TestOpenJSONL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	if err := os.WriteFile(path, []byte(`{"id":"1","stream_id":"product-1","type":"product.created"}`+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	src, closeSrc, err := Open(path, "events")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer closeSrc()
+	if _, ok := src.(*jsonl.Consumer); !ok {
+		t.Fatalf("expected a JSONL consumer, got %T", src)
+	}
+	if _, ok := src.(Counter); ok {
+		t.Error("expected JSONL sources to be scanned, not counted")
+	}
+	if batch, _, err := src.Fetch(context.Background(), nil, 10); err != nil || len(batch) != 1 {
+		t.Errorf("expected 1 event, got %d / %v", len(batch), err)
+	}
+
+	if _, _, err := Open(filepath.Join(t.TempDir(), "missing"), "events"); err == nil {
+		t.Error("expected an error for a missing path")
+	}
+}
+
+func TestRowID(t *testing.T) {
+	if got := rowID([]byte{0x2a, 0, 0, 0, 0, 0, 0, 0}); got != 42 {
+		t.Errorf("expected row id 42, got %d", got)
+	}
+	if got := rowID(nil); got != 0 {
+		t.Errorf("expected the beginning for an empty cursor, got %d", got)
+	}
+}
@@ -0,0 +1,269 @@
+package jsonl
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/shogotsuneto/go-simple-es-projector/cursor"
+	es "github.com/shogotsuneto/go-simple-eventstore"
+)
+
+// Compile-time interface compliance check
+var _ es.Consumer = (*Consumer)(nil)
+
+// Consumer reads events from a JSON Lines file, or from the files of a directory in
+// name order (e.g. rotated exports). Gzip-compressed files are detected by content.
+// Blank lines are skipped; a malformed line fails Fetch with its file and offset.
+//
+// Cursors are composite cursors (see package cursor) holding the current file name
+// and the byte offset after the last delivered line, in the decompressed content.
+// Reaching the end returns an empty batch with the cursor unchanged, so a Worker
+// polls for lines appended later. Set Follow when files are still being written.
+type Consumer struct {
+	Path    string // a file, or a directory of files
+	Pattern string // for directories: glob matched against file names; default: all files
+	Fields  Fields // JSON field mapping; zero value uses DefaultFields
+	Follow  bool   // files may still grow: an unterminated last line waits for its newline
+
+	mu   sync.Mutex
+	open *reader // kept between fetches to continue sequential reads
+}
+
+// reader is an open file positioned at offset
+type reader struct {
+	name   string
+	offset int64
+	file   *os.File
+	gz     *gzip.Reader
+	buf    *bufio.Reader
+}
+
+func (r *reader) close() {
+	if r.gz != nil {
+		_ = r.gz.Close()
+	}
+	_ = r.file.Close()
+}
+
+// Fetch implements es.Consumer.
+func (c *Consumer) Fetch(ctx context.Context, from es.Cursor, limit int) ([]es.Envelope, es.Cursor, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	name, offset, err := decodeCursor(from)
+	if err != nil {
+		return nil, nil, err
+	}
+	files, err := c.files()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(files) == 0 {
+		return nil, from, nil
+	}
+
+	i := 0
+	if name != "" {
+		i = sort.SearchStrings(files, name)
+		if i == len(files) || files[i] != name {
+			return nil, nil, fmt.Errorf("jsonl: file %q from cursor not found in %s", name, c.Path)
+		}
+	}
+
+	fields := c.Fields.withDefaults()
+	var batch []es.Envelope
+	for len(batch) < limit {
+		if err := ctx.Err(); err != nil {
+			return nil, nil, err
+		}
+
+		line, start, err := c.readLine(files[i], offset, i == len(files)-1)
+		if errors.Is(err, io.EOF) {
+			if i == len(files)-1 {
+				break // caught up
+			}
+			i, offset = i+1, 0 // rotated: continue with the next file
+			name = files[i]
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		name, offset = files[i], start+int64(len(line))
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+
+		env, err := fields.decode(line)
+		if err != nil {
+			return nil, nil, fmt.Errorf("jsonl: %s at offset %d: %w", files[i], start, err)
+		}
+		env.Partition = files[i]
+		env.Offset = strconv.FormatInt(start, 10)
+		batch = append(batch, env)
+	}
+
+	if name == "" {
+		return batch, from, nil
+	}
+	return batch, encodeCursor(name, offset), nil
+}
+
+// readLine reads the line starting at offset of file name. It returns io.EOF when
+// the file has no further complete line (or, unless following the last file, when
+// it is fully read).
+func (c *Consumer) readLine(name string, offset int64, last bool) ([]byte, int64, error) {
+	r := c.open
+	if r == nil || r.name != name || r.offset != offset {
+		c.closeReader()
+		var err error
+		if r, err = c.openAt(name, offset); err != nil {
+			return nil, 0, err
+		}
+		c.open = r
+	}
+
+	line, err := r.buf.ReadBytes('\n')
+	if err == nil || (errors.Is(err, io.EOF) && len(line) > 0 && !(c.Follow && last)) {
+		r.offset += int64(len(line))
+		return line, offset, nil
+	}
+
+	// EOF (possibly mid-line): reopen next time to pick up appended data
+	c.closeReader()
+	if errors.Is(err, io.EOF) {
+		return nil, 0, io.EOF
+	}
+	return nil, 0, fmt.Errorf("jsonl: read %s: %w", name, err)
+}
+
+// openAt opens file name positioned at offset in its decompressed content
+func (c *Consumer) openAt(name string, offset int64) (*reader, error) {
+	f, err := os.Open(c.pathOf(name))
+	if err != nil {
+		return nil, fmt.Errorf("jsonl: %w", err)
+	}
+	r := &reader{name: name, offset: offset, file: f}
+
+	var magic [2]byte
+	n, _ := io.ReadFull(f, magic[:])
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		r.close()
+		return nil, fmt.Errorf("jsonl: %w", err)
+	}
+
+	if n == 2 && magic == [2]byte{0x1f, 0x8b} {
+		if r.gz, err = gzip.NewReader(f); err != nil {
+			r.close()
+			return nil, fmt.Errorf("jsonl: %s: %w", name, err)
+		}
+		r.buf = bufio.NewReader(r.gz)
+		if _, err := r.buf.Discard(int(offset)); err != nil {
+			r.close()
+			return nil, fmt.Errorf("jsonl: %s: offset %d beyond end: %w", name, offset, err)
+		}
+		return r, nil
+	}
+
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		r.close()
+		return nil, fmt.Errorf("jsonl: %w", err)
+	}
+	r.buf = bufio.NewReader(f)
+	return r, nil
+}
+
+// files lists the files to read, sorted by name
+func (c *Consumer) files() ([]string, error) {
+	info, err := os.Stat(c.Path)
+	if err != nil {
+		return nil, fmt.Errorf("jsonl: %w", err)
+	}
+	if !info.IsDir() {
+		return []string{filepath.Base(c.Path)}, nil
+	}
+
+	entries, err := os.ReadDir(c.Path)
+	if err != nil {
+		return nil, fmt.Errorf("jsonl: %w", err)
+	}
+	var names []string
+	for _, e := range entries {
+		if !e.Type().IsRegular() {
+			continue
+		}
+		if c.Pattern != "" {
+			if ok, err := filepath.Match(c.Pattern, e.Name()); err != nil || !ok {
+				continue
+			}
+		}
+		names = append(names, e.Name())
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// pathOf returns the path of a file listed by files
+func (c *Consumer) pathOf(name string) string {
+	if info, err := os.Stat(c.Path); err == nil && !info.IsDir() {
+		return c.Path
+	}
+	return filepath.Join(c.Path, name)
+}
+
+// Commit implements es.Consumer; files are read-only, so it is a no-op.
+func (c *Consumer) Commit(ctx context.Context, next es.Cursor) error {
+	return nil
+}
+
+// Close releases the file kept open between fetches.
+func (c *Consumer) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closeReader()
+	return nil
+}
+
+func (c *Consumer) closeReader() {
+	if c.open != nil {
+		c.open.close()
+		c.open = nil
+	}
+}
+
+// encodeCursor stores the file name as a sub-cursor name and the offset as 8-byte little-endian
+func encodeCursor(name string, offset int64) es.Cursor {
+	return cursor.Encode(cursor.Composite{Positions: map[string]cursor.Position{
+		name: {Cursor: binary.LittleEndian.AppendUint64(nil, uint64(offset))},
+	}})
+}
+
+func decodeCursor(c es.Cursor) (string, int64, error) {
+	comp, err := cursor.Decode(c)
+	if err != nil {
+		return "", 0, fmt.Errorf("jsonl: %w", err)
+	}
+	if len(comp.Positions) > 1 {
+		return "", 0, fmt.Errorf("jsonl: %w: expected one file position", cursor.ErrInvalid)
+	}
+	for name, p := range comp.Positions {
+		if len(p.Cursor) != 8 {
+			return "", 0, fmt.Errorf("jsonl: %w: bad offset", cursor.ErrInvalid)
+		}
+		return name, int64(binary.LittleEndian.Uint64(p.Cursor)), nil
+	}
+	return "", 0, nil
+}
@@ -0,0 +1,200 @@
+package jsonl
+
+import (
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	es "github.com/shogotsuneto/go-simple-eventstore"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeGzip(t *testing.T, path, content string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := gzip.NewWriter(f)
+	if _, err := zw.Write([]byte(content)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func line(id string) string {
+	return `{"id":"` + id + `","stream_id":"product-1","type":"product.tag_added","version":1,"data":{"tag":"x"}}` + "\n"
+}
+
+func fetchIDs(t *testing.T, c *Consumer, from es.Cursor, limit int) (string, es.Cursor) {
+	t.Helper()
+	batch, next, err := c.Fetch(context.Background(), from, limit)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var ids []string
+	for _, ev := range batch {
+		ids = append(ids, ev.Event.ID)
+	}
+	return strings.Join(ids, " "), next
+}
+
+func TestConsumerDefaultFields(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	writeFile(t, path, `{"id":"e1","stream_id":"product-1","type":"product.tag_added","version":"3",`+
+		`"data":{"tag":"sale"},"metadata":{"source":"export","attempt":2},"timestamp":"2024-05-01T12:00:00Z"}`+"\n\n")
+
+	c := &Consumer{Path: path}
+	defer c.Close()
+	batch, _, err := c.Fetch(context.Background(), nil, 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(batch) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(batch))
+	}
+
+	ev := batch[0]
+	if ev.Event.ID != "e1" || ev.StreamID != "product-1" || ev.Event.Type != "product.tag_added" || ev.Event.Version != 3 {
+		t.Errorf("unexpected envelope: %+v", ev)
+	}
+	if string(ev.Event.Data) != `{"tag":"sale"}` {
+		t.Errorf("expected raw JSON data, got %s", ev.Event.Data)
+	}
+	if ev.Event.Metadata["source"] != "export" || ev.Event.Metadata["attempt"] != "2" {
+		t.Errorf("unexpected metadata: %v", ev.Event.Metadata)
+	}
+	if !ev.Event.Timestamp.Equal(time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected timestamp: %v", ev.Event.Timestamp)
+	}
+	if ev.Partition != "events.jsonl" || ev.Offset != "0" {
+		t.Errorf("unexpected diagnostics: %q %q", ev.Partition, ev.Offset)
+	}
+}
+
+func TestConsumerCustomFields(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dump.ndjson")
+	writeFile(t, path, `{"event":{"uuid":"e1","name":"order.placed","payload":"raw text"},"aggregate":"order-9","seq":4,"at":1714564800}`+"\n")
+
+	c := &Consumer{Path: path, Fields: Fields{
+		ID: "event.uuid", Type: "event.name", Data: "event.payload",
+		StreamID: "aggregate", Version: "seq", Timestamp: "at",
+	}}
+	defer c.Close()
+	batch, _, err := c.Fetch(context.Background(), nil, 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ev := batch[0]
+	if ev.Event.ID != "e1" || ev.Event.Type != "order.placed" || ev.StreamID != "order-9" || ev.Event.Version != 4 {
+		t.Errorf("unexpected envelope: %+v", ev)
+	}
+	if string(ev.Event.Data) != "raw text" {
+		t.Errorf("expected string data unquoted, got %q", ev.Event.Data)
+	}
+	if ev.Event.Timestamp.Unix() != 1714564800 {
+		t.Errorf("unexpected timestamp: %v", ev.Event.Timestamp)
+	}
+}
+
+func TestConsumerResumesFromCursor(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	writeFile(t, path, line("e1")+line("e2")+line("e3"))
+
+	c := &Consumer{Path: path}
+	defer c.Close()
+	ids, next := fetchIDs(t, c, nil, 2)
+	if ids != "e1 e2" {
+		t.Fatalf("expected e1 e2, got %s", ids)
+	}
+
+	// A fresh consumer (e.g. after a restart) continues at the byte offset
+	fresh := &Consumer{Path: path}
+	defer fresh.Close()
+	ids, next = fetchIDs(t, fresh, next, 10)
+	if ids != "e3" {
+		t.Errorf("expected e3, got %s", ids)
+	}
+
+	ids, again := fetchIDs(t, fresh, next, 10)
+	if ids != "" || string(again) != string(next) {
+		t.Errorf("expected empty batch with unchanged cursor at the end, got %q", ids)
+	}
+}
+
+func TestConsumerRotatedGzipDirectory(t *testing.T) {
+	dir := t.TempDir()
+	writeGzip(t, filepath.Join(dir, "events-001.jsonl.gz"), line("e1")+line("e2"))
+	writeGzip(t, filepath.Join(dir, "events-002.jsonl.gz"), line("e3"))
+	writeFile(t, filepath.Join(dir, "events-003.jsonl"), line("e4"))
+	writeFile(t, filepath.Join(dir, "README"), "not events\n")
+
+	c := &Consumer{Path: dir, Pattern: "events-*"}
+	defer c.Close()
+
+	var got []string
+	var from es.Cursor
+	for i := 0; i < 5; i++ {
+		ids, next := fetchIDs(t, c, from, 3)
+		if ids == "" {
+			break
+		}
+		got = append(got, ids)
+		from = next
+	}
+	if strings.Join(got, "|") != "e1 e2 e3|e4" {
+		t.Errorf("expected e1 e2 e3|e4, got %q", got)
+	}
+
+	// Resuming inside a compressed file
+	_, mid := fetchIDs(t, &Consumer{Path: dir, Pattern: "events-*"}, nil, 1)
+	if ids, _ := fetchIDs(t, &Consumer{Path: dir, Pattern: "events-*"}, mid, 1); ids != "e2" {
+		t.Errorf("expected e2 after resuming in a gzip file, got %s", ids)
+	}
+}
+
+func TestConsumerFollowWaitsForCompleteLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	partial := line("e2")
+	writeFile(t, path, line("e1")+partial[:10])
+
+	c := &Consumer{Path: path, Follow: true}
+	defer c.Close()
+	ids, next := fetchIDs(t, c, nil, 10)
+	if ids != "e1" {
+		t.Fatalf("expected only the complete line, got %s", ids)
+	}
+
+	writeFile(t, path, line("e1")+partial+line("e3"))
+	if ids, _ := fetchIDs(t, c, next, 10); ids != "e2 e3" {
+		t.Errorf("expected appended lines e2 e3, got %s", ids)
+	}
+}
+
+func TestConsumerReportsMalformedLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	writeFile(t, path, line("e1")+"{not json\n")
+
+	c := &Consumer{Path: path}
+	defer c.Close()
+	_, _, err := c.Fetch(context.Background(), nil, 10)
+	if err == nil || !strings.Contains(err.Error(), "events.jsonl at offset") {
+		t.Errorf("expected error naming file and offset, got %v", err)
+	}
+}
@@ -0,0 +1,9 @@
+// Package jsonl reads and writes events as JSON Lines (NDJSON) files, e.g. to replay a
+// production event export into a local projection.
+//
+// A Consumer reads a single file or a directory of rotated files, plain or
+// gzip-compressed, and can follow files as new lines are appended. Cursors are byte
+// offsets into the (decompressed) files; Fields maps JSON fields to es.Envelope.
+//
+// Export goes the other way, writing any es.Consumer's events as JSON Lines.
+package jsonl
@@ -0,0 +1,145 @@
+package jsonl
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"time"
+	"unicode/utf8"
+
+	"github.com/shogotsuneto/go-simple-es-projector"
+	es "github.com/shogotsuneto/go-simple-eventstore"
+)
+
+// ExportOptions configures Export.
+type ExportOptions struct {
+	Start     es.Cursor                   // resume position; nil starts at the beginning
+	Filter    projector.Filter            // optional; e.g. projector.TypeMatches, projector.TimeRange
+	Gzip      bool                        // gzip-compress the output
+	BatchSize int                         // default: 256
+	Logger    func(msg string, kv ...any) // optional, nil-safe; passed to the Worker
+}
+
+// ExportResult describes a finished (or interrupted) Export.
+type ExportResult struct {
+	Cursor es.Cursor // position after the last exported batch; pass as Start to continue
+	Events int       // events written
+}
+
+// exportLine is the layout written by Export and read with DefaultFields
+type exportLine struct {
+	ID        string            `json:"id"`
+	StreamID  string            `json:"stream_id"`
+	Type      string            `json:"type"`
+	Version   int64             `json:"version"`
+	Data      json.RawMessage   `json:"data,omitempty"`
+	Encoding  string            `json:"data_encoding,omitempty"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	Timestamp time.Time         `json:"timestamp,omitzero"`
+}
+
+// Export reads src from opts.Start until it is caught up and writes every event kept by
+// opts.Filter to w as one JSON line in the DefaultFields layout, so a Consumer can
+// replay the output byte for byte. Data that is compact JSON is embedded as is, other
+// text as a JSON string and anything that is not UTF-8 as base64 (marked with
+// "data_encoding": "base64").
+//
+// It drives src with a projector.Worker (StopAtEnd), so fetching behaves as in
+// Worker.Run, but src.Commit is never called. Output is flushed after every batch;
+// on error the result still holds the cursor of the last batch written.
+func Export(ctx context.Context, src es.Consumer, w io.Writer, opts ExportOptions) (ExportResult, error) {
+	res := ExportResult{Cursor: opts.Start}
+
+	out := w
+	var zw *gzip.Writer
+	if opts.Gzip {
+		zw = gzip.NewWriter(w)
+		out = zw
+	}
+	buf := bufio.NewWriter(out)
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+
+	worker := &projector.Worker{
+		Source:    readOnly{src},
+		Start:     opts.Start,
+		Filter:    opts.Filter,
+		BatchSize: opts.BatchSize,
+		StopAtEnd: true,
+		Logger:    opts.Logger,
+		Apply: func(ctx context.Context, batch []es.Envelope, next es.Cursor) error {
+			for _, env := range batch {
+				if err := enc.Encode(encodeLine(env)); err != nil {
+					return err
+				}
+			}
+			if err := buf.Flush(); err != nil {
+				return err
+			}
+			if zw != nil {
+				if err := zw.Flush(); err != nil {
+					return err
+				}
+			}
+			res.Cursor = next
+			res.Events += len(batch)
+			return nil
+		},
+	}
+
+	err := worker.Run(ctx)
+	if zw != nil {
+		if cerr := zw.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return res, err
+}
+
+func encodeLine(env es.Envelope) exportLine {
+	l := exportLine{
+		ID:        env.Event.ID,
+		StreamID:  env.StreamID,
+		Type:      env.Event.Type,
+		Version:   env.Event.Version,
+		Metadata:  env.Event.Metadata,
+		Timestamp: env.Event.Timestamp,
+	}
+	switch data := env.Event.Data; {
+	case len(data) == 0:
+	case !utf8.Valid(data):
+		l.Data, _ = json.Marshal(data) // []byte marshals as base64
+		l.Encoding = "base64"
+	case embeddable(data):
+		l.Data = data
+	default:
+		// A JSON string would be unquoted on replay, so quote it (and other text) again
+		l.Data, _ = json.Marshal(string(data))
+	}
+	return l
+}
+
+// embeddable reports whether data replays unchanged when written as raw JSON: the
+// encoder compacts it, a string is unquoted and null reads as missing
+func embeddable(data []byte) bool {
+	if data[0] == '"' || string(data) == "null" || !json.Valid(data) {
+		return false
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, data); err != nil {
+		return false
+	}
+	return bytes.Equal(compact.Bytes(), data)
+}
+
+// readOnly hides Commit so exporting does not move the source's committed position
+type readOnly struct {
+	es.Consumer
+}
+
+func (readOnly) Commit(ctx context.Context, next es.Cursor) error {
+	return nil
+}
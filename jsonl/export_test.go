@@ -0,0 +1,143 @@
+package jsonl
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shogotsuneto/go-simple-es-projector"
+	"github.com/shogotsuneto/go-simple-es-projector/memory"
+	es "github.com/shogotsuneto/go-simple-eventstore"
+)
+
+func TestExportRoundTrip(t *testing.T) {
+	src := &memory.Consumer{}
+	ts := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	src.Append("product-1",
+		es.Event{Type: "product.tag_added", Data: []byte(`{"tag":"sale"}`), Metadata: map[string]string{"source": "api"}, Timestamp: ts},
+		es.Event{Type: "product.renamed", Data: []byte(`"quoted"`), Timestamp: ts},
+		es.Event{Type: "product.note", Data: []byte("not json <b>"), Timestamp: ts},
+	)
+
+	var out bytes.Buffer
+	res, err := Export(context.Background(), src, &out, ExportOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Events != 3 || string(res.Cursor) != string(memory.Cursor(3)) {
+		t.Errorf("expected 3 events up to cursor 3, got %d / %x", res.Events, res.Cursor)
+	}
+
+	path := filepath.Join(t.TempDir(), "export.jsonl")
+	writeFile(t, path, out.String())
+	c := &Consumer{Path: path}
+	defer c.Close()
+	replayed, _, err := c.Fetch(context.Background(), nil, 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	original, _, _ := src.Fetch(context.Background(), nil, 10)
+	if len(replayed) != len(original) {
+		t.Fatalf("expected %d replayed events, got %d", len(original), len(replayed))
+	}
+	for i, want := range original {
+		got := replayed[i]
+		if got.Event.ID != want.Event.ID || got.StreamID != want.StreamID || got.Event.Type != want.Event.Type ||
+			got.Event.Version != want.Event.Version || !got.Event.Timestamp.Equal(want.Event.Timestamp) {
+			t.Errorf("event %d: expected %+v, got %+v", i, want, got)
+		}
+		if !bytes.Equal(got.Event.Data, want.Event.Data) {
+			t.Errorf("event %d: expected data %q, got %q", i, want.Event.Data, got.Event.Data)
+		}
+	}
+	if replayed[0].Event.Metadata["source"] != "api" {
+		t.Errorf("expected metadata to survive, got %v", replayed[0].Event.Metadata)
+	}
+}
+
+func TestExportRoundTripIsByteExact(t *testing.T) {
+	payloads := [][]byte{
+		{0x0a, 0xff, 0xfe, 0x01},        // binary, e.g. bytea
+		[]byte("{\"tag\": \"sale\"}\n"), // JSON that is not compact
+		[]byte(`null`),
+		[]byte(`{"html":"<b>"}`),
+		[]byte("  \"padded\""),
+	}
+	src := &memory.Consumer{}
+	for _, data := range payloads {
+		src.Append("product-1", es.Event{Type: "product.blob", Data: data})
+	}
+
+	var out bytes.Buffer
+	if _, err := Export(context.Background(), src, &out, ExportOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), `"data":"Cv/+AQ==","data_encoding":"base64"`) {
+		t.Errorf("expected binary data as marked base64, got:\n%s", out.String())
+	}
+
+	path := filepath.Join(t.TempDir(), "export.jsonl")
+	writeFile(t, path, out.String())
+	c := &Consumer{Path: path}
+	defer c.Close()
+	replayed, _, err := c.Fetch(context.Background(), nil, 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(replayed) != len(payloads) {
+		t.Fatalf("expected %d replayed events, got %d", len(payloads), len(replayed))
+	}
+	for i, want := range payloads {
+		if got := replayed[i].Event.Data; !bytes.Equal(got, want) {
+			t.Errorf("event %d: expected data %x, got %x", i, want, got)
+		}
+	}
+}
+
+func TestExportFilterGzipAndResume(t *testing.T) {
+	src := &memory.Consumer{}
+	src.Append("product-1", es.Event{Type: "product.tag_added"}, es.Event{Type: "product.renamed"})
+	src.Append("order-1", es.Event{Type: "order.placed"})
+
+	opts := ExportOptions{
+		Filter:    projector.All(projector.TypeMatches("product.*"), projector.StreamMatches("product-*")),
+		Gzip:      true,
+		BatchSize: 1,
+	}
+	var out bytes.Buffer
+	res, err := Export(context.Background(), src, &out, opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The filtered-out event still advances the resume cursor
+	if res.Events != 2 || string(res.Cursor) != string(memory.Cursor(3)) {
+		t.Errorf("expected 2 events up to cursor 3, got %d / %x", res.Events, res.Cursor)
+	}
+
+	zr, err := gzip.NewReader(&out)
+	if err != nil {
+		t.Fatalf("expected gzip output: %v", err)
+	}
+	plain, _ := io.ReadAll(zr)
+	if n := strings.Count(string(plain), "\n"); n != 2 || strings.Contains(string(plain), "order.placed") {
+		t.Errorf("expected 2 product lines, got %q", plain)
+	}
+
+	// Continuing from the returned cursor only exports new events
+	src.Append("product-2", es.Event{Type: "product.tag_added"})
+	out.Reset()
+	opts.Start, opts.Gzip = res.Cursor, false
+	res, err = Export(context.Background(), src, &out, opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Events != 1 || !strings.Contains(out.String(), `"stream_id":"product-2"`) {
+		t.Errorf("expected only the new event, got %q", out.String())
+	}
+}
@@ -0,0 +1,177 @@
+package jsonl
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	es "github.com/shogotsuneto/go-simple-eventstore"
+)
+
+// Fields maps the JSON fields of a line to es.Envelope. Paths may be dotted to reach
+// into nested objects (e.g. "event.type"); empty fields use DefaultFields, which match
+// the lines written by Export.
+type Fields struct {
+	ID        string // default: "id"
+	StreamID  string // default: "stream_id"
+	Type      string // default: "type"
+	Version   string // default: "version"; a number or numeric string
+	Data      string // default: "data"; a JSON string becomes its contents, any other value its raw JSON
+	Metadata  string // default: "metadata"; an object, non-string values keep their raw JSON
+	Timestamp string // default: "timestamp"; an RFC 3339 string or Unix seconds
+
+	DataEncoding string // default: "data_encoding"; "base64" decodes Data from a base64 string
+}
+
+// DefaultFields is the mapping used when Consumer.Fields is the zero value.
+var DefaultFields = Fields{
+	ID:        "id",
+	StreamID:  "stream_id",
+	Type:      "type",
+	Version:   "version",
+	Data:      "data",
+	Metadata:  "metadata",
+	Timestamp: "timestamp",
+
+	DataEncoding: "data_encoding",
+}
+
+// withDefaults fills empty paths from DefaultFields
+func (f Fields) withDefaults() Fields {
+	pick := func(v, def string) string {
+		if v == "" {
+			return def
+		}
+		return v
+	}
+	return Fields{
+		ID:        pick(f.ID, DefaultFields.ID),
+		StreamID:  pick(f.StreamID, DefaultFields.StreamID),
+		Type:      pick(f.Type, DefaultFields.Type),
+		Version:   pick(f.Version, DefaultFields.Version),
+		Data:      pick(f.Data, DefaultFields.Data),
+		Metadata:  pick(f.Metadata, DefaultFields.Metadata),
+		Timestamp: pick(f.Timestamp, DefaultFields.Timestamp),
+
+		DataEncoding: pick(f.DataEncoding, DefaultFields.DataEncoding),
+	}
+}
+
+// decode maps one JSON line to an envelope; missing fields stay zero
+func (f Fields) decode(line []byte) (es.Envelope, error) {
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(line, &obj); err != nil {
+		return es.Envelope{}, err
+	}
+
+	var env es.Envelope
+	var err error
+	if env.Event.ID, err = stringField(obj, f.ID); err != nil {
+		return es.Envelope{}, err
+	}
+	if env.StreamID, err = stringField(obj, f.StreamID); err != nil {
+		return es.Envelope{}, err
+	}
+	if env.Event.Type, err = stringField(obj, f.Type); err != nil {
+		return es.Envelope{}, err
+	}
+
+	if raw, ok := lookup(obj, f.Version); ok {
+		s := strings.Trim(string(raw), `"`)
+		if env.Event.Version, err = strconv.ParseInt(s, 10, 64); err != nil {
+			return es.Envelope{}, fmt.Errorf("%s: invalid version %s", f.Version, raw)
+		}
+	}
+
+	if raw, ok := lookup(obj, f.Data); ok {
+		encoding, err := stringField(obj, f.DataEncoding)
+		if err != nil {
+			return es.Envelope{}, err
+		}
+		var s string
+		switch {
+		case encoding == "base64":
+			if json.Unmarshal(raw, &s) != nil {
+				return es.Envelope{}, fmt.Errorf("%s: expected a base64 string, got %s", f.Data, raw)
+			}
+			if env.Event.Data, err = base64.StdEncoding.DecodeString(s); err != nil {
+				return es.Envelope{}, fmt.Errorf("%s: invalid base64: %w", f.Data, err)
+			}
+		case encoding != "":
+			return es.Envelope{}, fmt.Errorf("%s: unknown encoding %q", f.DataEncoding, encoding)
+		case json.Unmarshal(raw, &s) == nil:
+			env.Event.Data = []byte(s)
+		default:
+			env.Event.Data = bytes.Clone(raw)
+		}
+	}
+
+	if raw, ok := lookup(obj, f.Metadata); ok {
+		var meta map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &meta); err != nil {
+			return es.Envelope{}, fmt.Errorf("%s: expected an object", f.Metadata)
+		}
+		env.Event.Metadata = make(map[string]string, len(meta))
+		for k, v := range meta {
+			var s string
+			if json.Unmarshal(v, &s) != nil {
+				s = string(v)
+			}
+			env.Event.Metadata[k] = s
+		}
+	}
+
+	if raw, ok := lookup(obj, f.Timestamp); ok {
+		if env.Event.Timestamp, err = parseTimestamp(raw); err != nil {
+			return es.Envelope{}, fmt.Errorf("%s: %w", f.Timestamp, err)
+		}
+	}
+	return env, nil
+}
+
+// lookup resolves a dotted path; JSON null counts as missing
+func lookup(obj map[string]json.RawMessage, path string) (json.RawMessage, bool) {
+	parts := strings.Split(path, ".")
+	for i, part := range parts {
+		raw, ok := obj[part]
+		if !ok || string(raw) == "null" {
+			return nil, false
+		}
+		if i == len(parts)-1 {
+			return raw, true
+		}
+		obj = nil
+		if json.Unmarshal(raw, &obj) != nil {
+			return nil, false
+		}
+	}
+	return nil, false
+}
+
+func stringField(obj map[string]json.RawMessage, path string) (string, error) {
+	raw, ok := lookup(obj, path)
+	if !ok {
+		return "", nil
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return "", fmt.Errorf("%s: expected a string, got %s", path, raw)
+	}
+	return s, nil
+}
+
+func parseTimestamp(raw json.RawMessage) (time.Time, error) {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return time.Parse(time.RFC3339Nano, s)
+	}
+	var secs float64
+	if err := json.Unmarshal(raw, &secs); err != nil {
+		return time.Time{}, fmt.Errorf("invalid timestamp %s", raw)
+	}
+	return time.Unix(0, int64(secs*float64(time.Second))).UTC(), nil
+}
@@ -0,0 +1,67 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/shogotsuneto/go-simple-es-projector"
+	es "github.com/shogotsuneto/go-simple-eventstore"
+)
+
+// Compile-time interface compliance check
+var _ projector.CheckpointAdmin = (*Checkpoints)(nil)
+
+// Checkpoints is an in-memory projector.CheckpointAdmin. The zero value is ready to use
+// and it is safe for concurrent use; Put doubles as the save call of an Apply.
+type Checkpoints struct {
+	Clock projector.Clock // used for UpdatedAt; nil uses the real clock
+
+	mu  sync.Mutex
+	cps map[string]projector.Checkpoint
+}
+
+// Load implements projector.CheckpointStore: the cursor of name, or nil if it has none.
+func (c *Checkpoints) Load(ctx context.Context, name string) (es.Cursor, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cp, ok := c.cps[name]
+	if !ok {
+		return nil, nil
+	}
+	return clone(cp.Cursor), nil
+}
+
+// Put stores the cursor of name.
+func (c *Checkpoints) Put(ctx context.Context, name string, cursor es.Cursor) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cps == nil {
+		c.cps = map[string]projector.Checkpoint{}
+	}
+	now := time.Now()
+	if c.Clock != nil {
+		now = c.Clock.Now()
+	}
+	c.cps[name] = projector.Checkpoint{Name: name, Cursor: clone(cursor), UpdatedAt: now}
+	return nil
+}
+
+// List returns every checkpoint, sorted by name.
+func (c *Checkpoints) List(ctx context.Context) ([]projector.Checkpoint, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cps := make([]projector.Checkpoint, 0, len(c.cps))
+	for _, cp := range c.cps {
+		cp.Cursor = clone(cp.Cursor)
+		cps = append(cps, cp)
+	}
+	sort.Slice(cps, func(i, j int) bool { return cps[i].Name < cps[j].Name })
+	return cps, nil
+}
+
+// clone copies a cursor; stored cursors are never nil, like a NOT NULL column
+func clone(cursor es.Cursor) es.Cursor {
+	return append(es.Cursor{}, cursor...)
+}
@@ -0,0 +1,35 @@
+package memory
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCheckpoints(t *testing.T) {
+	ctx := context.Background()
+	cps := &Checkpoints{}
+
+	if cur, err := cps.Load(ctx, "product_tags"); err != nil || cur != nil {
+		t.Fatalf("expected nil cursor for an unknown projection, got %x / %v", cur, err)
+	}
+
+	_ = cps.Put(ctx, "product_tags", Cursor(3))
+	_ = cps.Put(ctx, "orders", nil) // reset to the beginning
+
+	cur, _ := cps.Load(ctx, "product_tags")
+	if string(cur) != string(Cursor(3)) {
+		t.Errorf("expected cursor 3, got %x", cur)
+	}
+	cur[0] = 9 // callers cannot modify the stored cursor
+	if again, _ := cps.Load(ctx, "product_tags"); string(again) != string(Cursor(3)) {
+		t.Errorf("expected stored cursor to be copied, got %x", again)
+	}
+	if reset, _ := cps.Load(ctx, "orders"); reset == nil || len(reset) != 0 {
+		t.Errorf("expected an empty (not missing) cursor after reset, got %#v", reset)
+	}
+
+	list, _ := cps.List(ctx)
+	if len(list) != 2 || list[0].Name != "orders" || list[1].Name != "product_tags" || list[1].UpdatedAt.IsZero() {
+		t.Errorf("expected checkpoints sorted by name, got %+v", list)
+	}
+}
@@ -0,0 +1,177 @@
+package memory
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/shogotsuneto/go-simple-es-projector"
+	es "github.com/shogotsuneto/go-simple-eventstore"
+)
+
+// Compile-time interface compliance checks
+var (
+	_ es.Consumer        = (*Consumer)(nil)
+	_ projector.Notifier = (*Consumer)(nil)
+)
+
+// Consumer is an in-memory, append-only event log implementing es.Consumer.
+// The zero value is ready to use and it is safe for concurrent use, so events can be
+// appended while a Worker consumes them.
+//
+// It also implements projector.Notifier, waking an idle worker on every Append.
+type Consumer struct {
+	BlockFor time.Duration   // optional; an empty Fetch waits up to this long for new events
+	Clock    projector.Clock // used for timestamps and BlockFor; nil uses the real clock
+
+	mu       sync.Mutex
+	events   []es.Envelope
+	streams  map[string]int64 // last version per stream
+	appended chan struct{}    // closed and replaced on every Append
+	waited   bool             // appended was handed out by Wait
+	missed   bool             // an Append happened while nobody waited
+}
+
+// Append adds events to streamID and returns the cursor after the last one.
+// Zero-valued fields are filled in: Version continues the stream, Timestamp is the
+// current time and ID is derived from the row ID.
+func (c *Consumer) Append(streamID string, events ...es.Event) es.Cursor {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.streams == nil {
+		c.streams = map[string]int64{}
+	}
+	now := time.Now()
+	if c.Clock != nil {
+		now = c.Clock.Now()
+	}
+	for _, ev := range events {
+		id := int64(len(c.events) + 1)
+		if ev.Version == 0 {
+			ev.Version = c.streams[streamID] + 1
+		}
+		if ev.Timestamp.IsZero() {
+			ev.Timestamp = now
+		}
+		if ev.ID == "" {
+			ev.ID = fmt.Sprintf("evt-%d", id)
+		}
+		c.streams[streamID] = ev.Version
+
+		c.events = append(c.events, es.Envelope{
+			Event:     ev,
+			StreamID:  streamID,
+			Partition: "memory",
+			Offset:    strconv.FormatInt(id, 10),
+		})
+	}
+
+	if len(events) > 0 {
+		// A channel only a blocking Fetch took (and may have given up on) does not
+		// count as a waiting Notifier
+		if !c.waited {
+			c.missed = true
+		}
+		if c.appended != nil {
+			close(c.appended)
+			c.appended = nil
+		}
+		c.waited = false
+	}
+	return Cursor(int64(len(c.events)))
+}
+
+// Len returns the number of events in the log.
+func (c *Consumer) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.events)
+}
+
+// Fetch implements es.Consumer: up to limit events strictly after cursor. An empty
+// fetch returns cursor unchanged, after waiting up to BlockFor for new events.
+func (c *Consumer) Fetch(ctx context.Context, cursor es.Cursor, limit int) ([]es.Envelope, es.Cursor, error) {
+	batch, next, wake := c.fetch(cursor, limit)
+	if len(batch) > 0 || c.BlockFor <= 0 {
+		return batch, next, nil
+	}
+
+	var timeout <-chan time.Time
+	if c.Clock != nil {
+		timeout = c.Clock.After(c.BlockFor)
+	} else {
+		timeout = time.After(c.BlockFor)
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	case <-timeout:
+		return nil, cursor, nil
+	case <-wake:
+		batch, next, _ = c.fetch(cursor, limit)
+		return batch, next, nil
+	}
+}
+
+// fetch reads a batch, or returns a channel closed by the next Append if there is none
+func (c *Consumer) fetch(cursor es.Cursor, limit int) ([]es.Envelope, es.Cursor, <-chan struct{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	start := int(rowID(cursor))
+	end := min(start+limit, len(c.events))
+	if start >= end {
+		return nil, cursor, c.waitLocked()
+	}
+
+	batch := make([]es.Envelope, end-start)
+	copy(batch, c.events[start:end])
+	return batch, Cursor(int64(end)), nil
+}
+
+// Commit implements es.Consumer; like the Postgres consumer it is a no-op.
+func (c *Consumer) Commit(ctx context.Context, cursor es.Cursor) error {
+	return nil
+}
+
+// Wait implements projector.Notifier: the channel is closed by the next Append, or
+// right away if events were appended since the last Wait, so none is missed between
+// a worker's empty Fetch and its Wait.
+func (c *Consumer) Wait(ctx context.Context) <-chan struct{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.missed {
+		c.missed = false
+		done := make(chan struct{})
+		close(done)
+		return done
+	}
+	c.waited = true
+	return c.waitLocked()
+}
+
+func (c *Consumer) waitLocked() <-chan struct{} {
+	if c.appended == nil {
+		c.appended = make(chan struct{})
+	}
+	return c.appended
+}
+
+// Cursor returns the cursor positioned after row id (1-based), as used by this
+// package and go-simple-eventstore's Postgres consumer.
+func Cursor(id int64) es.Cursor {
+	return binary.LittleEndian.AppendUint64(nil, uint64(id))
+}
+
+// rowID decodes a cursor; anything shorter than 8 bytes is the beginning
+func rowID(cursor es.Cursor) int64 {
+	if len(cursor) < 8 {
+		return 0
+	}
+	return int64(binary.LittleEndian.Uint64(cursor[:8]))
+}
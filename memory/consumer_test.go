@@ -0,0 +1,119 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shogotsuneto/go-simple-es-projector"
+	es "github.com/shogotsuneto/go-simple-eventstore"
+)
+
+func TestConsumerCursorSemantics(t *testing.T) {
+	c := &Consumer{}
+	c.Append("product-1", es.Event{Type: "product.created"}, es.Event{Type: "product.tag_added"})
+	last := c.Append("product-2", es.Event{ID: "custom", Type: "product.created", Version: 7})
+
+	if string(last) != string(Cursor(3)) {
+		t.Errorf("expected Append to return cursor 3, got %x", last)
+	}
+
+	batch, next, err := c.Fetch(context.Background(), nil, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(batch) != 2 || string(next) != string(Cursor(2)) {
+		t.Fatalf("expected 2 events up to cursor 2, got %d / %x", len(batch), next)
+	}
+	if ev := batch[1]; ev.Event.ID != "evt-2" || ev.Event.Version != 2 || ev.StreamID != "product-1" || ev.Offset != "2" || ev.Event.Timestamp.IsZero() {
+		t.Errorf("unexpected defaults: %+v", ev)
+	}
+
+	batch, next, _ = c.Fetch(context.Background(), next, 10)
+	if len(batch) != 1 || batch[0].Event.ID != "custom" || batch[0].Event.Version != 7 {
+		t.Errorf("expected explicit fields to be kept, got %+v", batch)
+	}
+
+	// Caught up: empty batch and the requested cursor back
+	batch, again, _ := c.Fetch(context.Background(), next, 10)
+	if len(batch) != 0 || string(again) != string(next) {
+		t.Errorf("expected empty fetch to return the cursor unchanged, got %d / %x", len(batch), again)
+	}
+}
+
+func TestConsumerBlockingFetch(t *testing.T) {
+	c := &Consumer{BlockFor: time.Second}
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		c.Append("product-1", es.Event{Type: "product.created"})
+	}()
+
+	started := time.Now()
+	batch, _, err := c.Fetch(context.Background(), nil, 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(batch) != 1 {
+		t.Fatalf("expected the appended event, got %d events", len(batch))
+	}
+	if time.Since(started) >= time.Second {
+		t.Error("expected Append to end the wait early")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, _, err := c.Fetch(ctx, Cursor(1), 10); err != context.DeadlineExceeded {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestConsumerWaitAfterBlockingFetchTimeout(t *testing.T) {
+	c := &Consumer{BlockFor: time.Millisecond}
+	if batch, _, err := c.Fetch(context.Background(), nil, 10); err != nil || len(batch) != 0 {
+		t.Fatalf("expected an empty fetch, got %d events / %v", len(batch), err)
+	}
+
+	// The Append lands between the timed-out Fetch and the worker's Wait
+	c.Append("product-1", es.Event{Type: "product.created"})
+
+	select {
+	case <-c.Wait(context.Background()):
+	default:
+		t.Fatal("expected Wait to report the Append made after the blocking fetch timed out")
+	}
+}
+
+func TestConsumerWithWorker(t *testing.T) {
+	c := &Consumer{}
+	c.Append("product-1", es.Event{Type: "product.created"})
+
+	applied := make(chan es.Envelope, 10)
+	w := &projector.Worker{
+		Source:    c,
+		Notifier:  c,
+		IdleSleep: time.Hour, // only the notifier can wake the worker in time
+		Apply: func(ctx context.Context, batch []es.Envelope, next es.Cursor) error {
+			for _, ev := range batch {
+				applied <- ev
+			}
+			return nil
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = w.Run(ctx) }()
+
+	<-applied
+	c.Append("product-1", es.Event{Type: "product.renamed"})
+
+	select {
+	case ev := <-applied:
+		if ev.Event.Type != "product.renamed" || ev.Event.Version != 2 {
+			t.Errorf("unexpected event %+v", ev)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("worker was not woken by Append")
+	}
+}
@@ -0,0 +1,7 @@
+// Package memory provides an in-memory es.Consumer and checkpoint store for local
+// development, demos and tests: projections can run against them without any database.
+//
+// Its cursors behave like those of go-simple-eventstore's Postgres consumer (8-byte
+// little-endian row IDs, empty fetches return the requested cursor), so code tested
+// against it behaves the same against Postgres.
+package memory
@@ -0,0 +1,7 @@
+// Package merge combines several es.Consumers into one, e.g. to build a projection
+// that joins events from two event stores.
+//
+// Events are interleaved by timestamp and the merged position is kept in a versioned
+// composite cursor holding every child's position, so a merged projection checkpoints
+// like any other.
+package merge
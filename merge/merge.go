@@ -0,0 +1,201 @@
+package merge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/shogotsuneto/go-simple-es-projector/cursor"
+	es "github.com/shogotsuneto/go-simple-eventstore"
+)
+
+// Compile-time interface compliance check
+var _ es.Consumer = (*Consumer)(nil)
+
+// Source is a named child consumer. The name identifies the source's position in the
+// composite cursor, so it must stay the same across deployments.
+type Source struct {
+	Name     string
+	Consumer es.Consumer
+}
+
+// Consumer merges several sources into one es.Consumer.
+//
+// Each Fetch fetches from every source and interleaves their events by
+// Event.Timestamp, breaking ties by source name; events of one source keep their
+// order. A source whose batch came back full stops the merge when its events run out,
+// since its next (unfetched) event may be older than the remaining ones of other
+// sources. A partially merged batch is fetched again up to its merged events to get
+// the source's cursor after them (falling back to a skip count in the cursor if the
+// source returns fewer events), so sources must return the same events for the same
+// cursor (as ordered stores such as Postgres do).
+//
+// Commit commits to every source the position up to which all of its events were
+// delivered. Sources missing from a cursor start from the beginning; a cursor naming
+// an unknown source is rejected.
+//
+// Cursors are cursor.Composite values holding one Position per source name, the
+// timestamp of the last delivered event and the number of events delivered so far;
+// render them with cursor.Describe.
+type Consumer struct {
+	sources []Source
+}
+
+// New returns a Consumer merging sources. Names must be unique and non-empty.
+func New(sources ...Source) (*Consumer, error) {
+	if len(sources) == 0 {
+		return nil, errors.New("merge: no sources")
+	}
+	seen := make(map[string]bool, len(sources))
+	for _, s := range sources {
+		if s.Name == "" || s.Consumer == nil {
+			return nil, errors.New("merge: source needs a name and a consumer")
+		}
+		if seen[s.Name] {
+			return nil, fmt.Errorf("merge: duplicate source %q", s.Name)
+		}
+		seen[s.Name] = true
+	}
+	return &Consumer{sources: sources}, nil
+}
+
+// pending is the fetched, not yet merged part of one source's batch
+type pending struct {
+	name   string
+	pos    cursor.Position
+	events []es.Envelope
+	next   es.Cursor // the source's cursor after its whole batch
+	full   bool      // the source may have more events after this batch
+	taken  int
+}
+
+// Fetch implements es.Consumer.
+func (c *Consumer) Fetch(ctx context.Context, from es.Cursor, limit int) ([]es.Envelope, es.Cursor, error) {
+	comp, err := c.decode(from)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sources := make([]*pending, len(c.sources))
+	for i, s := range c.sources {
+		pos := comp.Positions[s.Name]
+		batch, next, err := s.Consumer.Fetch(ctx, pos.Cursor, pos.Skip+limit)
+		if err != nil {
+			return nil, nil, fmt.Errorf("merge: fetch %q: %w", s.Name, err)
+		}
+
+		p := &pending{name: s.Name, pos: pos, next: next, full: len(batch) >= pos.Skip+limit}
+		if pos.Skip < len(batch) {
+			p.events = batch[pos.Skip:]
+		}
+		sources[i] = p
+	}
+
+	var out []es.Envelope
+	for len(out) < limit {
+		var best *pending
+		stop := false
+		for _, p := range sources {
+			if p.taken == len(p.events) {
+				if p.full {
+					stop = true // this source's next event is unknown
+				}
+				continue
+			}
+			if best == nil || before(p, best) {
+				best = p
+			}
+		}
+		if stop || best == nil {
+			break
+		}
+		out = append(out, best.events[best.taken])
+		best.taken++
+	}
+
+	for i, p := range sources {
+		pos, err := p.advance(ctx, c.sources[i].Consumer)
+		if err != nil {
+			return nil, nil, fmt.Errorf("merge: fetch %q: %w", p.name, err)
+		}
+		comp.Positions[p.name] = pos
+	}
+	if len(out) > 0 {
+		comp.Timestamp = out[len(out)-1].Event.Timestamp
+		comp.Events += int64(len(out))
+	}
+	return out, cursor.Encode(comp), nil
+}
+
+// advance returns the source's position after the merged events. A partially merged
+// batch is refetched up to its merged events for the source's cursor after them, so the
+// skip does not grow with every Fetch.
+func (p *pending) advance(ctx context.Context, src es.Consumer) (cursor.Position, error) {
+	if p.taken == len(p.events) && p.next != nil {
+		return cursor.Position{Cursor: p.next}, nil // whole batch delivered
+	}
+	skip := p.pos.Skip + p.taken
+	if p.taken > 0 {
+		batch, next, err := src.Fetch(ctx, p.pos.Cursor, skip)
+		if err != nil {
+			return cursor.Position{}, err
+		}
+		if len(batch) == skip && next != nil {
+			return cursor.Position{Cursor: next}, nil
+		}
+	}
+	return cursor.Position{Cursor: p.pos.Cursor, Skip: skip}, nil // nothing merged, or the source came back short
+}
+
+// Commit implements es.Consumer.
+func (c *Consumer) Commit(ctx context.Context, next es.Cursor) error {
+	comp, err := c.decode(next)
+	if err != nil {
+		return err
+	}
+	for _, s := range c.sources {
+		pos := comp.Positions[s.Name]
+		if pos.Cursor == nil {
+			continue // nothing fully delivered yet
+		}
+		if err := s.Consumer.Commit(ctx, pos.Cursor); err != nil {
+			return fmt.Errorf("merge: commit %q: %w", s.Name, err)
+		}
+	}
+	return nil
+}
+
+// decode parses a composite cursor and checks it only names known sources
+func (c *Consumer) decode(b es.Cursor) (cursor.Composite, error) {
+	comp, err := cursor.Decode(b)
+	if err != nil {
+		return cursor.Composite{}, err
+	}
+	for name, pos := range comp.Positions {
+		if !c.has(name) {
+			return cursor.Composite{}, fmt.Errorf("%w: unknown source %q", cursor.ErrInvalid, name)
+		}
+		if pos.Skip < 0 {
+			return cursor.Composite{}, fmt.Errorf("%w: negative skip for source %q", cursor.ErrInvalid, name)
+		}
+	}
+	return comp, nil
+}
+
+func (c *Consumer) has(name string) bool {
+	for _, s := range c.sources {
+		if s.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// before orders the head events of two sources by timestamp, then by source name
+func before(a, b *pending) bool {
+	ta, tb := a.events[a.taken].Event.Timestamp, b.events[b.taken].Event.Timestamp
+	if !ta.Equal(tb) {
+		return ta.Before(tb)
+	}
+	return a.name < b.name
+}
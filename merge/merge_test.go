@@ -0,0 +1,186 @@
+package merge
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shogotsuneto/go-simple-es-projector/cursor"
+	es "github.com/shogotsuneto/go-simple-eventstore"
+)
+
+// logConsumer serves a fixed event log with 8-byte little-endian index cursors
+type logConsumer struct {
+	events  []es.Envelope
+	commits []es.Cursor
+}
+
+func (l *logConsumer) Fetch(ctx context.Context, cursor es.Cursor, limit int) ([]es.Envelope, es.Cursor, error) {
+	start := 0
+	if len(cursor) == 8 {
+		start = int(binary.LittleEndian.Uint64(cursor))
+	}
+	end := min(start+limit, len(l.events))
+	if start >= end {
+		return nil, cursor, nil
+	}
+	return l.events[start:end], binary.LittleEndian.AppendUint64(nil, uint64(end)), nil
+}
+
+func (l *logConsumer) Commit(ctx context.Context, cursor es.Cursor) error {
+	l.commits = append(l.commits, cursor)
+	return nil
+}
+
+func at(id string, sec int) es.Envelope {
+	return es.Envelope{Event: es.Event{ID: id, Timestamp: time.Unix(int64(sec), 0)}}
+}
+
+func ids(batch []es.Envelope) string {
+	var out []string
+	for _, ev := range batch {
+		out = append(out, ev.Event.ID)
+	}
+	return strings.Join(out, " ")
+}
+
+func TestConsumerMergesByTimestamp(t *testing.T) {
+	products := &logConsumer{events: []es.Envelope{at("p1", 1), at("p2", 3), at("p3", 5), at("p4", 7)}}
+	orders := &logConsumer{events: []es.Envelope{at("o1", 2), at("o2", 3), at("o3", 4)}}
+	c, err := New(Source{Name: "products", Consumer: products}, Source{Name: "orders", Consumer: orders})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got []string
+	var from es.Cursor
+	for i := 0; i < 10; i++ {
+		batch, next, err := c.Fetch(context.Background(), from, 3)
+		if err != nil {
+			t.Fatalf("fetch %d: %v", i, err)
+		}
+		if len(batch) == 0 {
+			break
+		}
+		got = append(got, ids(batch))
+		from = next
+	}
+
+	// Ties (o2/p2 at 3s) go to the source name that sorts first
+	want := []string{"p1 o1 o2", "p2 o3 p3", "p4"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("expected batches %q, got %q", want, got)
+	}
+
+	if err := c.Commit(context.Background(), from); err != nil {
+		t.Fatalf("unexpected commit error: %v", err)
+	}
+	if len(products.commits) != 1 || binary.LittleEndian.Uint64(products.commits[0]) != 4 {
+		t.Errorf("expected products committed at 4, got %v", products.commits)
+	}
+	if len(orders.commits) != 1 || binary.LittleEndian.Uint64(orders.commits[0]) != 3 {
+		t.Errorf("expected orders committed at 3, got %v", orders.commits)
+	}
+}
+
+func TestConsumerWaitsForFullSource(t *testing.T) {
+	// products returns a full batch of old events; orders' event is newer than all of them
+	products := &logConsumer{events: []es.Envelope{at("p1", 1), at("p2", 2), at("p3", 3)}}
+	orders := &logConsumer{events: []es.Envelope{at("o1", 10)}}
+	c, _ := New(Source{Name: "products", Consumer: products}, Source{Name: "orders", Consumer: orders})
+
+	batch, next, err := c.Fetch(context.Background(), nil, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ids(batch) != "p1 p2" {
+		t.Errorf("expected p1 p2, got %s", ids(batch))
+	}
+
+	comp, _ := cursor.Decode(next)
+	if pos := comp.Positions["orders"]; pos.Cursor != nil || pos.Skip != 0 {
+		t.Errorf("expected orders untouched, got %+v", pos)
+	}
+	if comp.Events != 2 || !comp.Timestamp.Equal(time.Unix(2, 0)) {
+		t.Errorf("expected 2 events up to 2s, got %d at %v", comp.Events, comp.Timestamp)
+	}
+
+	batch, _, _ = c.Fetch(context.Background(), next, 2)
+	if ids(batch) != "p3 o1" {
+		t.Errorf("expected p3 o1, got %s", ids(batch))
+	}
+}
+
+func TestConsumerKeepsSkipBoundedWhenInterleaved(t *testing.T) {
+	// Evenly interleaved sources never have a whole batch merged in one Fetch
+	products, orders := &logConsumer{}, &logConsumer{}
+	for i := 0; i < 1000; i++ {
+		products.events = append(products.events, at(fmt.Sprintf("p%d", i), 2*i))
+		orders.events = append(orders.events, at(fmt.Sprintf("o%d", i), 2*i+1))
+	}
+	c, _ := New(Source{Name: "products", Consumer: products}, Source{Name: "orders", Consumer: orders})
+
+	var from es.Cursor
+	total := 0
+	for {
+		batch, next, err := c.Fetch(context.Background(), from, 10)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(batch) == 0 {
+			break
+		}
+		total += len(batch)
+		from = next
+
+		comp, _ := cursor.Decode(next)
+		for name, pos := range comp.Positions {
+			if pos.Skip != 0 {
+				t.Fatalf("expected %s to resume from a source cursor after %d events, got skip %d", name, total, pos.Skip)
+			}
+		}
+	}
+	if total != 2000 {
+		t.Errorf("expected all 2000 events, got %d", total)
+	}
+}
+
+func TestConsumerRejectsUnknownSource(t *testing.T) {
+	c, _ := New(Source{Name: "products", Consumer: &logConsumer{}})
+
+	unknown := cursor.Encode(cursor.Composite{Positions: map[string]cursor.Position{"payments": {}}})
+	if _, _, err := c.Fetch(context.Background(), unknown, 10); !errors.Is(err, cursor.ErrInvalid) {
+		t.Errorf("expected cursor.ErrInvalid, got %v", err)
+	}
+
+	negative := cursor.Encode(cursor.Composite{Positions: map[string]cursor.Position{"products": {Skip: -1}}})
+	if _, _, err := c.Fetch(context.Background(), negative, 10); !errors.Is(err, cursor.ErrInvalid) {
+		t.Errorf("expected cursor.ErrInvalid for a negative skip, got %v", err)
+	}
+
+	if _, err := New(Source{Name: "a", Consumer: &logConsumer{}}, Source{Name: "a", Consumer: &logConsumer{}}); err == nil {
+		t.Error("expected duplicate source names to be rejected")
+	}
+}
+
+func TestConsumerReadsVersion1Cursors(t *testing.T) {
+	products := &logConsumer{events: []es.Envelope{at("p1", 1), at("p2", 2), at("p3", 3)}}
+	c, _ := New(Source{Name: "products", Consumer: products})
+
+	// Written by the first release: products at index 1, one event of the next batch delivered
+	v1 := es.Cursor("ESM\x01\x01\x08products\x01\x08\x01\x00\x00\x00\x00\x00\x00\x00")
+	batch, next, err := c.Fetch(context.Background(), v1, 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ids(batch) != "p3" {
+		t.Errorf("expected p3, got %s", ids(batch))
+	}
+	if comp, _ := cursor.Decode(next); comp.Version != 2 {
+		t.Errorf("expected next cursor in version 2, got v%d", comp.Version)
+	}
+}
@@ -0,0 +1,152 @@
+package projector
+
+import (
+	"context"
+	"time"
+
+	es "github.com/shogotsuneto/go-simple-eventstore"
+)
+
+// Middleware wraps an ApplyFunc to add cross-cutting behavior (timing, logging, tracing…).
+type Middleware func(next ApplyFunc) ApplyFunc
+
+// Chain composes middlewares into one; the first middleware is the outermost,
+// so Chain(a, b)(fn) is a(b(fn)). Nil middlewares are skipped.
+func Chain(mws ...Middleware) Middleware {
+	return func(fn ApplyFunc) ApplyFunc {
+		for i := len(mws) - 1; i >= 0; i-- {
+			if mws[i] != nil {
+				fn = mws[i](fn)
+			}
+		}
+		return fn
+	}
+}
+
+type committedCursorKey struct{}
+
+// CommittedCursor returns the cursor of the last batch the worker committed before the
+// current Apply call (Worker.Start for the first batch). Middlewares that split a batch
+// pass it to intermediate chunks so the checkpoint never moves past unapplied events.
+func CommittedCursor(ctx context.Context) (es.Cursor, bool) {
+	c, ok := ctx.Value(committedCursorKey{}).(es.Cursor)
+	return c, ok
+}
+
+func withCommittedCursor(ctx context.Context, c es.Cursor) context.Context {
+	return context.WithValue(ctx, committedCursorKey{}, c)
+}
+
+// Recover converts a panic inside Apply into a *PanicError. The worker already recovers
+// panics from Apply; use Recover to catch them closer to the handler, e.g. below MaxBatch
+// so the error names the chunk that panicked, or when calling an ApplyFunc directly.
+func Recover() Middleware {
+	return func(next ApplyFunc) ApplyFunc {
+		return func(ctx context.Context, batch []es.Envelope, cursor es.Cursor) (err error) {
+			committed, _ := CommittedCursor(ctx)
+			defer recoverPanic(PhaseApply, committed, cursor, batch, &err)
+			return next(ctx, batch, cursor)
+		}
+	}
+}
+
+// Timeout cancels the context passed to Apply after d, measured on the worker's Clock.
+// Apply must honor the context for the timeout to take effect.
+func Timeout(d time.Duration) Middleware {
+	return func(next ApplyFunc) ApplyFunc {
+		return func(ctx context.Context, batch []es.Envelope, cursor es.Cursor) error {
+			ctx, cancel := withClockTimeout(ctx, clockFrom(ctx), d)
+			defer cancel()
+			return next(ctx, batch, cursor)
+		}
+	}
+}
+
+// withClockTimeout is context.WithTimeout on clock
+func withClockTimeout(ctx context.Context, clock Clock, d time.Duration) (context.Context, context.CancelFunc) {
+	if _, ok := clock.(realClock); ok {
+		return context.WithTimeout(ctx, d)
+	}
+
+	deadline := clock.Now().Add(d)
+	inner, cancel := context.WithCancelCause(ctx)
+	timer := clock.NewTimer(d)
+	go func() {
+		select {
+		case <-timer.C():
+			cancel(context.DeadlineExceeded)
+		case <-inner.Done():
+		}
+	}()
+	return clockDeadline{Context: inner, deadline: deadline}, func() {
+		timer.Stop()
+		cancel(context.Canceled)
+	}
+}
+
+// clockDeadline reports a Clock timeout like context.WithTimeout does
+type clockDeadline struct {
+	context.Context
+	deadline time.Time
+}
+
+func (c clockDeadline) Deadline() (time.Time, bool) {
+	if d, ok := c.Context.Deadline(); ok && d.Before(c.deadline) {
+		return d, true
+	}
+	return c.deadline, true
+}
+
+func (c clockDeadline) Err() error {
+	err := c.Context.Err()
+	if err != nil && context.Cause(c.Context) == context.DeadlineExceeded {
+		return context.DeadlineExceeded
+	}
+	return err
+}
+
+// LogEvents logs every event before Apply and the outcome of the batch afterwards,
+// timed on the worker's Clock.
+func LogEvents(logf func(msg string, kv ...any)) Middleware {
+	return func(next ApplyFunc) ApplyFunc {
+		return func(ctx context.Context, batch []es.Envelope, cursor es.Cursor) error {
+			for _, ev := range batch {
+				logf("applying event", "eventID", ev.Event.ID, "type", ev.Event.Type, "streamID", ev.StreamID, "version", ev.Event.Version)
+			}
+
+			clock := clockFrom(ctx)
+			started := clock.Now()
+			err := next(ctx, batch, cursor)
+			took := clock.Now().Sub(started)
+			if err != nil {
+				logf("apply failed", "eventCount", len(batch), "took", took, "error", err)
+				return err
+			}
+			logf("apply succeeded", "eventCount", len(batch), "took", took)
+			return nil
+		}
+	}
+}
+
+// MaxBatch splits batches larger than n into consecutive Apply calls of at most n events,
+// e.g. to keep transactions short. Intermediate chunks are applied with the
+// CommittedCursor, so only the last chunk moves the checkpoint to 'next'; a crash in
+// between replays the whole batch, which idempotent Apply tolerates.
+func MaxBatch(n int) Middleware {
+	return func(next ApplyFunc) ApplyFunc {
+		return func(ctx context.Context, batch []es.Envelope, cursor es.Cursor) error {
+			if n <= 0 || len(batch) <= n {
+				return next(ctx, batch, cursor)
+			}
+
+			committed, _ := CommittedCursor(ctx)
+			for len(batch) > n {
+				if err := next(ctx, batch[:n], committed); err != nil {
+					return err
+				}
+				batch = batch[n:]
+			}
+			return next(ctx, batch, cursor)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package projector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	es "github.com/shogotsuneto/go-simple-eventstore"
+)
+
+func TestChainOrder(t *testing.T) {
+	var calls []string
+	trace := func(name string) Middleware {
+		return func(next ApplyFunc) ApplyFunc {
+			return func(ctx context.Context, batch []es.Envelope, cursor es.Cursor) error {
+				calls = append(calls, name+">")
+				err := next(ctx, batch, cursor)
+				calls = append(calls, "<"+name)
+				return err
+			}
+		}
+	}
+
+	apply := Chain(trace("a"), nil, trace("b"))(func(ctx context.Context, batch []es.Envelope, cursor es.Cursor) error {
+		calls = append(calls, "apply")
+		return nil
+	})
+	if err := apply(context.Background(), nil, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := strings.Join(calls, " "); got != "a> b> apply <b <a" {
+		t.Errorf("unexpected call order: %s", got)
+	}
+}
+
+func TestRecover(t *testing.T) {
+	apply := Recover()(func(ctx context.Context, batch []es.Envelope, cursor es.Cursor) error {
+		panic("boom")
+	})
+
+	batch := []es.Envelope{createTestEvent("1", "test.event")}
+	err := apply(withCommittedCursor(context.Background(), es.Cursor("cursor0")), batch, es.Cursor("cursor1"))
+
+	var perr *PanicError
+	if !errors.As(err, &perr) {
+		t.Fatalf("expected *PanicError, got %v", err)
+	}
+	if perr.Phase != PhaseApply || perr.Value != "boom" || string(perr.Cursor) != "cursor0" || string(perr.Next) != "cursor1" {
+		t.Errorf("unexpected panic error: %+v", perr)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	apply := Timeout(10 * time.Millisecond)(func(ctx context.Context, batch []es.Envelope, cursor es.Cursor) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	if err := apply(context.Background(), nil, nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestLogEvents(t *testing.T) {
+	var logs []logEntry
+	logf := func(msg string, kv ...any) { logs = append(logs, logEntry{msg: msg, kv: kv}) }
+
+	applyErr := errors.New("apply failed")
+	apply := LogEvents(logf)(func(ctx context.Context, batch []es.Envelope, cursor es.Cursor) error {
+		return applyErr
+	})
+
+	batch := []es.Envelope{createTestEvent("1", "test.event"), createTestEvent("2", "test.event")}
+	if err := apply(context.Background(), batch, nil); err != applyErr {
+		t.Fatalf("expected apply error, got %v", err)
+	}
+
+	var msgs []string
+	for _, l := range logs {
+		msgs = append(msgs, l.msg)
+	}
+	if got := strings.Join(msgs, ","); got != "applying event,applying event,apply failed" {
+		t.Errorf("unexpected log messages: %s", got)
+	}
+}
+
+func TestMaxBatchWithWorker(t *testing.T) {
+	consumer := newFakeConsumer()
+	consumer.AddBatch([]es.Envelope{createTestEvent("1", "e"), createTestEvent("2", "e"), createTestEvent("3", "e")}, es.Cursor("cursor1"))
+	consumer.AddBatch([]es.Envelope{createTestEvent("4", "e"), createTestEvent("5", "e")}, es.Cursor("cursor2"))
+
+	var applied []string
+	worker := &Worker{
+		Source:     consumer,
+		Start:      es.Cursor("start"),
+		IdleSleep:  time.Millisecond,
+		Middleware: []Middleware{MaxBatch(2)},
+		Apply: func(ctx context.Context, batch []es.Envelope, next es.Cursor) error {
+			applied = append(applied, fmt.Sprintf("%v@%s", eventIDsOf(batch), next))
+			return nil
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := worker.Run(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+
+	// Intermediate chunks keep the previously committed cursor
+	want := "[1 2]@start [3]@cursor1 [4 5]@cursor2"
+	if got := strings.Join(applied, " "); got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+}
@@ -0,0 +1,15 @@
+package projector
+
+import "context"
+
+// Notifier wakes an idle Worker as soon as new events may be available,
+// instead of waiting for the full IdleSleep.
+//
+// Wait returns a channel that receives a value when the worker should poll again.
+// Notifications are hints only: the worker keeps polling every IdleSleep, so a lost
+// notification delays delivery by at most one IdleSleep.
+// Implementations should remember a notification that arrives while nobody is waiting,
+// so an append racing with an empty Fetch is not missed.
+type Notifier interface {
+	Wait(ctx context.Context) <-chan struct{}
+}
@@ -0,0 +1,55 @@
+package projector
+
+import "time"
+
+// PollStrategy decides how long an idle Worker waits before polling the source again.
+// Implement it to plug in your own backoff policy.
+type PollStrategy interface {
+	// IdleDelay returns the wait after the n-th consecutive empty Fetch (n >= 1).
+	// The count resets whenever a Fetch returns events.
+	IdleDelay(n int) time.Duration
+}
+
+// FixedPoll waits the same duration after every empty Fetch.
+// It is the strategy used when Worker.Poll is nil (with IdleSleep as the duration).
+type FixedPoll time.Duration
+
+// IdleDelay implements PollStrategy.
+func (p FixedPoll) IdleDelay(n int) time.Duration {
+	return time.Duration(p)
+}
+
+// ExponentialPoll starts at Min after activity and grows the wait geometrically by
+// Factor on each consecutive empty Fetch, up to Max. Any batch with events resets
+// it to Min, so bursts are picked up quickly while quiet periods cost few queries.
+type ExponentialPoll struct {
+	Min    time.Duration // default: 50ms
+	Max    time.Duration // default: 5s
+	Factor float64       // default: 2
+}
+
+// IdleDelay implements PollStrategy.
+func (p ExponentialPoll) IdleDelay(n int) time.Duration {
+	lo, hi, factor := p.Min, p.Max, p.Factor
+	if lo <= 0 {
+		lo = 50 * time.Millisecond
+	}
+	if hi <= 0 {
+		hi = 5 * time.Second
+	}
+	if hi < lo {
+		hi = lo
+	}
+	if factor <= 1 {
+		factor = 2
+	}
+
+	d := float64(lo)
+	for i := 1; i < n; i++ {
+		d *= factor
+		if d >= float64(hi) {
+			return hi
+		}
+	}
+	return time.Duration(d)
+}
@@ -0,0 +1,139 @@
+package projector_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shogotsuneto/go-simple-es-projector"
+	"github.com/shogotsuneto/go-simple-es-projector/projectortest"
+	es "github.com/shogotsuneto/go-simple-eventstore"
+)
+
+func TestExponentialPollIdleDelay(t *testing.T) {
+	p := projector.ExponentialPoll{Min: 10 * time.Millisecond, Max: 70 * time.Millisecond, Factor: 2}
+
+	want := []time.Duration{
+		10 * time.Millisecond,
+		20 * time.Millisecond,
+		40 * time.Millisecond,
+		70 * time.Millisecond, // capped
+		70 * time.Millisecond,
+	}
+	for i, w := range want {
+		if got := p.IdleDelay(i + 1); got != w {
+			t.Errorf("IdleDelay(%d): expected %v, got %v", i+1, w, got)
+		}
+	}
+}
+
+func TestExponentialPollDefaults(t *testing.T) {
+	p := projector.ExponentialPoll{}
+
+	if got := p.IdleDelay(1); got != 50*time.Millisecond {
+		t.Errorf("expected default min 50ms, got %v", got)
+	}
+	if got := p.IdleDelay(1000); got != 5*time.Second {
+		t.Errorf("expected default max 5s, got %v", got)
+	}
+}
+
+// runIdle runs worker until the clock has been advanced n times and returns the sleeps
+func runIdle(t *testing.T, worker *projector.Worker, clock *projectortest.FakeClock, n int) []time.Duration {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() { done <- worker.Run(ctx) }()
+
+	var sleeps []time.Duration
+	for i := 0; i < n; i++ {
+		sleeps = append(sleeps, clock.AdvanceNext())
+	}
+
+	clock.BlockUntil(1)
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	return sleeps
+}
+
+func TestWorkerPollStrategySleepSequence(t *testing.T) {
+	// Three empty polls, activity, then more empty polls
+	consumer := projectortest.NewConsumer().
+		AddEmpty().AddEmpty().AddEmpty().
+		AddBatch(es.Cursor("cursor1"), projectortest.NewEvent("1", "test.event"))
+	clock := projectortest.NewFakeClock(time.Unix(0, 0))
+
+	worker := &projector.Worker{
+		Source: consumer,
+		Start:  es.Cursor("start"),
+		Poll:   projector.ExponentialPoll{Min: 10 * time.Millisecond, Max: 30 * time.Millisecond, Factor: 2},
+		Clock:  clock,
+		Apply: func(ctx context.Context, batch []es.Envelope, next es.Cursor) error {
+			return nil
+		},
+	}
+
+	sleeps := runIdle(t, worker, clock, 5)
+
+	want := []time.Duration{
+		10 * time.Millisecond,
+		20 * time.Millisecond,
+		30 * time.Millisecond,
+		10 * time.Millisecond, // reset after activity
+		20 * time.Millisecond,
+	}
+	for i := range want {
+		if sleeps[i] != want[i] {
+			t.Errorf("sleep %d: expected %v, got %v", i, want[i], sleeps[i])
+		}
+	}
+}
+
+func TestWorkerDefaultPollUsesIdleSleep(t *testing.T) {
+	clock := projectortest.NewFakeClock(time.Unix(0, 0))
+
+	worker := &projector.Worker{
+		Source:    projectortest.NewConsumer(),
+		Start:     es.Cursor("start"),
+		IdleSleep: 42 * time.Millisecond,
+		Clock:     clock,
+		Apply: func(ctx context.Context, batch []es.Envelope, next es.Cursor) error {
+			return nil
+		},
+	}
+
+	for i, d := range runIdle(t, worker, clock, 3) {
+		if d != 42*time.Millisecond {
+			t.Errorf("sleep %d: expected IdleSleep 42ms, got %v", i, d)
+		}
+	}
+}
+
+func TestWorkerIdleSleepWithFakeClockIsInstant(t *testing.T) {
+	clock := projectortest.NewFakeClock(time.Unix(0, 0))
+
+	worker := &projector.Worker{
+		Source:    projectortest.NewConsumer(),
+		Start:     es.Cursor("start"),
+		IdleSleep: time.Hour,
+		Clock:     clock,
+		Apply: func(ctx context.Context, batch []es.Envelope, next es.Cursor) error {
+			return nil
+		},
+	}
+
+	started := time.Now()
+	runIdle(t, worker, clock, 24)
+
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Errorf("expected a day of idle sleeps to finish instantly, took %v", elapsed)
+	}
+	if got := clock.Now(); !got.Equal(time.Unix(0, 0).Add(24 * time.Hour)) {
+		t.Errorf("expected fake clock to advance 24h, got %v", got)
+	}
+}
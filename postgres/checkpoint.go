@@ -0,0 +1,111 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/shogotsuneto/go-simple-es-projector"
+	es "github.com/shogotsuneto/go-simple-eventstore"
+)
+
+// Checkpoints stores one cursor per projection in the checkpoint table layout of
+// examples/pg_to_pg. It implements projector.CheckpointAdmin, for a Supervisor and
+// cmd/projectorctl; save the cursor from Apply with Save, in the projection's transaction:
+//
+//	cps := &postgres.Checkpoints{DB: db}
+//	worker.Apply = postgres.InTx(db, func(ctx context.Context, tx *sql.Tx, batch []es.Envelope, next es.Cursor) error {
+//		// ... project batch ...
+//		return cps.Save(ctx, tx, "product_tags", next)
+//	})
+type Checkpoints struct {
+	DB    *sql.DB // used by Load, List and Put
+	Table string  // default: "projection_checkpoints"; may be schema-qualified
+}
+
+// table returns the quoted table name
+func (c *Checkpoints) table() string {
+	if c.Table == "" {
+		return quoteTable("projection_checkpoints")
+	}
+	return quoteTable(c.Table)
+}
+
+// CreateTableSQL returns idempotent DDL for the checkpoint table.
+func (c *Checkpoints) CreateTableSQL() string {
+	return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	projection_name TEXT PRIMARY KEY,
+	cursor_value BYTEA NOT NULL,
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+`, c.table())
+}
+
+// Load returns the saved cursor of projection, or nil if it has none yet.
+func (c *Checkpoints) Load(ctx context.Context, projection string) (es.Cursor, error) {
+	var cursor []byte
+	err := c.DB.QueryRowContext(ctx,
+		fmt.Sprintf(`SELECT cursor_value FROM %s WHERE projection_name = $1`, c.table()),
+		projection).Scan(&cursor)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load checkpoint: %w", err)
+	}
+	return es.Cursor(cursor), nil
+}
+
+// Save upserts the cursor of projection within tx.
+func (c *Checkpoints) Save(ctx context.Context, tx *sql.Tx, projection string, cursor es.Cursor) error {
+	_, err := tx.ExecContext(ctx,
+		fmt.Sprintf(`INSERT INTO %s (projection_name, cursor_value, updated_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT (projection_name)
+DO UPDATE SET cursor_value = EXCLUDED.cursor_value, updated_at = NOW()`, c.table()),
+		projection, []byte(cursor))
+	if err != nil {
+		return fmt.Errorf("failed to save checkpoint: %w", err)
+	}
+	return nil
+}
+
+// Put upserts the cursor of projection outside any projection transaction, e.g. to
+// reset it. An empty cursor restarts the projection from the beginning.
+func (c *Checkpoints) Put(ctx context.Context, projection string, cursor es.Cursor) error {
+	tx, err := c.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	if err := c.Save(ctx, tx, projection, append(es.Cursor{}, cursor...)); err != nil { // never NULL
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// List returns every stored checkpoint, sorted by projection name.
+func (c *Checkpoints) List(ctx context.Context) ([]projector.Checkpoint, error) {
+	rows, err := c.DB.QueryContext(ctx,
+		fmt.Sprintf(`SELECT projection_name, cursor_value, updated_at FROM %s ORDER BY projection_name`, c.table()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list checkpoints: %w", err)
+	}
+	defer rows.Close()
+
+	var cps []projector.Checkpoint
+	for rows.Next() {
+		var cp projector.Checkpoint
+		var cursor []byte
+		if err := rows.Scan(&cp.Name, &cursor, &cp.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to list checkpoints: %w", err)
+		}
+		cp.Cursor = es.Cursor(cursor)
+		cps = append(cps, cp)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list checkpoints: %w", err)
+	}
+	return cps, nil
+}
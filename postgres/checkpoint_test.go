@@ -0,0 +1,33 @@
+package postgres
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/shogotsuneto/go-simple-es-projector"
+)
+
+var _ projector.CheckpointAdmin = (*Checkpoints)(nil)
+
+func TestCheckpointsCreateTableSQL(t *testing.T) {
+	sql := (&Checkpoints{}).CreateTableSQL()
+	for _, want := range []string{
+		`CREATE TABLE IF NOT EXISTS "projection_checkpoints"`,
+		"projection_name TEXT PRIMARY KEY",
+		"cursor_value BYTEA NOT NULL",
+	} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("expected DDL to contain %q, got:\n%s", want, sql)
+		}
+	}
+
+	custom := (&Checkpoints{Table: "read_model_checkpoints"}).CreateTableSQL()
+	if !strings.Contains(custom, `CREATE TABLE IF NOT EXISTS "read_model_checkpoints"`) {
+		t.Errorf("expected custom table name, got:\n%s", custom)
+	}
+
+	qualified := (&Checkpoints{Table: `ReadModels.checkpoints"; --`}).CreateTableSQL()
+	if !strings.Contains(qualified, `CREATE TABLE IF NOT EXISTS "ReadModels"."checkpoints""; --"`) {
+		t.Errorf("expected schema and table quoted separately, got:\n%s", qualified)
+	}
+}
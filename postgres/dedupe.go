@@ -0,0 +1,148 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	es "github.com/shogotsuneto/go-simple-eventstore"
+)
+
+// Deduper records applied event IDs in a Postgres table, in the same transaction
+// as the projection, so a batch and its "already applied" marks commit or roll back
+// together. Combined with InTx it gives effectively-once projection:
+//
+//	dedupe := &postgres.Deduper{Projection: "product_tags"}
+//	worker.Apply = postgres.InTx(db, dedupe.Wrap(projectBatchTx))
+//
+// Create the table with CreateTableSQL and bound its size with Prune.
+type Deduper struct {
+	Projection string // projection name; lets projections share the table
+	Table      string // default: "projection_applied_events"; may be schema-qualified
+}
+
+// name returns the configured table name
+func (d *Deduper) name() string {
+	if d.Table == "" {
+		return "projection_applied_events"
+	}
+	return d.Table
+}
+
+// table returns the quoted table name
+func (d *Deduper) table() string {
+	return quoteTable(d.name())
+}
+
+// CreateTableSQL returns idempotent DDL for the applied-events table.
+func (d *Deduper) CreateTableSQL() string {
+	return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %[1]s (
+	projection_name TEXT NOT NULL,
+	event_id TEXT NOT NULL,
+	applied_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+	PRIMARY KEY (projection_name, event_id)
+);
+CREATE INDEX IF NOT EXISTS %[2]s ON %[1]s(applied_at);
+`, d.table(), pq.QuoteIdentifier("idx_"+safeName(d.name())+"_applied_at"))
+}
+
+// Wrap returns a TxApplyFunc that passes fn only the events of the batch not yet
+// recorded, then records them in the same transaction. fn is still called (with an
+// empty batch) when every event is a duplicate, so the checkpoint advances.
+func (d *Deduper) Wrap(fn TxApplyFunc) TxApplyFunc {
+	return func(ctx context.Context, tx *sql.Tx, batch []es.Envelope, next es.Cursor) error {
+		fresh, err := d.Filter(ctx, tx, batch)
+		if err != nil {
+			return err
+		}
+		if err := fn(ctx, tx, fresh, next); err != nil {
+			return err
+		}
+		return d.Mark(ctx, tx, fresh)
+	}
+}
+
+// Filter returns the events of batch whose IDs are not recorded yet, in order.
+// Repeated IDs within batch are dropped as well; events without an ID are kept.
+func (d *Deduper) Filter(ctx context.Context, tx *sql.Tx, batch []es.Envelope) ([]es.Envelope, error) {
+	ids := eventIDs(batch)
+	if len(ids) == 0 {
+		return batch, nil
+	}
+
+	rows, err := tx.QueryContext(ctx,
+		fmt.Sprintf(`SELECT event_id FROM %s WHERE projection_name = $1 AND event_id = ANY($2)`, d.table()),
+		d.Projection, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query applied events: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[string]bool)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan applied event: %w", err)
+		}
+		applied[id] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating applied events: %w", err)
+	}
+
+	out := make([]es.Envelope, 0, len(batch))
+	for _, ev := range batch {
+		id := ev.Event.ID
+		if id != "" {
+			if applied[id] {
+				continue
+			}
+			applied[id] = true
+		}
+		out = append(out, ev)
+	}
+	return out, nil
+}
+
+// Mark records the events of batch as applied.
+func (d *Deduper) Mark(ctx context.Context, tx *sql.Tx, batch []es.Envelope) error {
+	ids := eventIDs(batch)
+	if len(ids) == 0 {
+		return nil
+	}
+
+	_, err := tx.ExecContext(ctx,
+		fmt.Sprintf(`INSERT INTO %s (projection_name, event_id)
+		 SELECT $1, unnest($2::text[])
+		 ON CONFLICT (projection_name, event_id) DO NOTHING`, d.table()),
+		d.Projection, pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("failed to record applied events: %w", err)
+	}
+	return nil
+}
+
+// Prune deletes records older than retention and returns how many were removed.
+// Choose a retention longer than any redelivery window of your source.
+func (d *Deduper) Prune(ctx context.Context, db *sql.DB, retention time.Duration) (int64, error) {
+	result, err := db.ExecContext(ctx,
+		fmt.Sprintf(`DELETE FROM %s WHERE projection_name = $1 AND applied_at < $2`, d.table()),
+		d.Projection, time.Now().Add(-retention))
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune applied events: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// eventIDs returns the non-empty event IDs of batch
+func eventIDs(batch []es.Envelope) []string {
+	ids := make([]string, 0, len(batch))
+	for _, ev := range batch {
+		if ev.Event.ID != "" {
+			ids = append(ids, ev.Event.ID)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,50 @@
+package postgres
+
+import (
+	"strings"
+	"testing"
+
+	es "github.com/shogotsuneto/go-simple-eventstore"
+)
+
+func TestDeduperCreateTableSQL(t *testing.T) {
+	d := &Deduper{Projection: "product_tags"}
+	sql := d.CreateTableSQL()
+
+	for _, want := range []string{
+		`CREATE TABLE IF NOT EXISTS "projection_applied_events"`,
+		"PRIMARY KEY (projection_name, event_id)",
+		`CREATE INDEX IF NOT EXISTS "idx_projection_applied_events_applied_at" ON "projection_applied_events"(applied_at)`,
+	} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("expected DDL to contain %q, got:\n%s", want, sql)
+		}
+	}
+
+	custom := (&Deduper{Table: "seen_events"}).CreateTableSQL()
+	if !strings.Contains(custom, `CREATE TABLE IF NOT EXISTS "seen_events"`) {
+		t.Errorf("expected custom table name, got:\n%s", custom)
+	}
+
+	qualified := (&Deduper{Table: `Projections.seen"; DROP TABLE x; --`}).CreateTableSQL()
+	for _, want := range []string{
+		`CREATE TABLE IF NOT EXISTS "Projections"."seen""; DROP TABLE x; --"`,
+		`CREATE INDEX IF NOT EXISTS "idx_projections_seen___drop_table_x_____applied_at"`,
+	} {
+		if !strings.Contains(qualified, want) {
+			t.Errorf("expected quoted schema and table names, got:\n%s", qualified)
+		}
+	}
+}
+
+func TestEventIDsSkipsEmpty(t *testing.T) {
+	ids := eventIDs([]es.Envelope{
+		{Event: es.Event{ID: "a"}},
+		{Event: es.Event{}},
+		{Event: es.Event{ID: "b"}},
+	})
+
+	if len(ids) != 2 || ids[0] != "a" || ids[1] != "b" {
+		t.Errorf("expected [a b], got %q", ids)
+	}
+}
@@ -0,0 +1,7 @@
+// Package postgres provides optional PostgreSQL helpers for projector workers.
+//
+// It targets the `events` table layout used by go-simple-eventstore's postgres
+// adapter and the checkpoint layout shown in examples/pg_to_pg. Nothing in the
+// core projector package depends on it; import it only if you use Postgres with
+// github.com/lib/pq.
+package postgres
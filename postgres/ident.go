@@ -0,0 +1,31 @@
+package postgres
+
+import (
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// quoteTable quotes a table name for SQL; a "schema.table" name is quoted part by part
+func quoteTable(name string) string {
+	parts := strings.Split(name, ".")
+	for i, p := range parts {
+		parts[i] = pq.QuoteIdentifier(p)
+	}
+	return strings.Join(parts, ".")
+}
+
+// safeName derives an identifier from name for objects named after a table: lowercase,
+// with characters other than letters, digits and underscores replaced by underscores
+func safeName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '_':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + 'a' - 'A'
+		default:
+			return '_'
+		}
+	}, name)
+}
@@ -0,0 +1,127 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/shogotsuneto/go-simple-es-projector"
+)
+
+// DefaultChannel is the LISTEN/NOTIFY channel used by TriggerSQL and NewNotifier
+// when no channel name is given.
+const DefaultChannel = "events_appended"
+
+// Compile-time interface compliance check
+var _ projector.Notifier = (*Notifier)(nil)
+
+// Notifier implements projector.Notifier using Postgres LISTEN/NOTIFY.
+//
+// It holds a dedicated connection (pq.Listener) that reconnects automatically.
+// Notifications are coalesced: any number of NOTIFYs received while the worker is
+// busy wake it up once. After a reconnect the worker is woken as well, because
+// notifications sent while disconnected are lost; the worker's IdleSleep polling
+// remains the fallback for anything else that gets dropped.
+type Notifier struct {
+	listener *pq.Listener
+	signal   chan struct{} // capacity 1; holds a pending wake-up
+	done     chan struct{}
+	closing  sync.Once
+}
+
+// NewNotifier connects to Postgres and LISTENs on channel (DefaultChannel if empty).
+// Install the matching trigger with TriggerSQL. Call Close when done.
+func NewNotifier(connStr, channel string) (*Notifier, error) {
+	if channel == "" {
+		channel = DefaultChannel
+	}
+
+	listener := pq.NewListener(connStr, 100*time.Millisecond, 10*time.Second, nil)
+	if err := listener.Listen(channel); err != nil {
+		_ = listener.Close()
+		return nil, fmt.Errorf("failed to listen on channel %q: %w", channel, err)
+	}
+
+	n := &Notifier{
+		listener: listener,
+		signal:   make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+	go n.loop()
+
+	return n, nil
+}
+
+// Wait returns a channel that receives a value once a notification is pending.
+func (n *Notifier) Wait(ctx context.Context) <-chan struct{} {
+	return n.signal
+}
+
+// Close stops listening and releases the connection. Calling it again does nothing.
+func (n *Notifier) Close() error {
+	var err error
+	n.closing.Do(func() {
+		close(n.done)
+		err = n.listener.Close()
+	})
+	return err
+}
+
+// loop forwards notifications to signal and pings the connection while idle
+func (n *Notifier) loop() {
+	for {
+		select {
+		case <-n.done:
+			return
+		case _, ok := <-n.listener.Notify:
+			if !ok {
+				return
+			}
+			// A nil notification means the connection was re-established;
+			// wake the worker anyway so it catches up on anything missed.
+			n.wake()
+		case <-time.After(90 * time.Second):
+			// Detect dead connections early; pq reconnects on failure
+			go func() { _ = n.listener.Ping() }()
+		}
+	}
+}
+
+// wake records a pending wake-up without blocking
+func (n *Notifier) wake() {
+	select {
+	case n.signal <- struct{}{}:
+	default:
+	}
+}
+
+// TriggerSQL returns the SQL that makes inserts into table NOTIFY channel
+// (DefaultChannel if empty). The trigger fires once per INSERT statement, so
+// appending a batch of events sends a single notification.
+//
+// table is a single identifier, quoted like go-simple-eventstore quotes its table name.
+// The function and trigger are named after it, with characters other than letters,
+// digits and underscores replaced by underscores.
+//
+// The statements are idempotent and can be run on every startup or put in a migration.
+func TriggerSQL(table, channel string) string {
+	if channel == "" {
+		channel = DefaultChannel
+	}
+	fn := pq.QuoteIdentifier(safeName(table) + "_notify")
+
+	return fmt.Sprintf(`CREATE OR REPLACE FUNCTION %[1]s() RETURNS trigger AS $$
+BEGIN
+	PERFORM pg_notify(%[2]s, '');
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS %[1]s ON %[3]s;
+CREATE TRIGGER %[1]s
+	AFTER INSERT ON %[3]s
+	FOR EACH STATEMENT EXECUTE FUNCTION %[1]s();
+`, fn, pq.QuoteLiteral(channel), pq.QuoteIdentifier(table))
+}
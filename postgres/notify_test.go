@@ -0,0 +1,82 @@
+package postgres
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+func TestTriggerSQL(t *testing.T) {
+	sql := TriggerSQL("events", "")
+
+	for _, want := range []string{
+		`CREATE OR REPLACE FUNCTION "events_notify"()`,
+		"pg_notify('events_appended', '')",
+		`DROP TRIGGER IF EXISTS "events_notify" ON "events"`,
+		`AFTER INSERT ON "events"`,
+		"FOR EACH STATEMENT",
+	} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("expected trigger SQL to contain %q, got:\n%s", want, sql)
+		}
+	}
+}
+
+func TestTriggerSQLQuotesChannel(t *testing.T) {
+	sql := TriggerSQL("events", "it's")
+
+	if !strings.Contains(sql, "pg_notify('it''s', '')") {
+		t.Errorf("expected channel to be quoted, got:\n%s", sql)
+	}
+}
+
+func TestTriggerSQLQuotesTable(t *testing.T) {
+	sql := TriggerSQL(`Events"; DROP TABLE x; --`, "")
+
+	for _, want := range []string{
+		`CREATE OR REPLACE FUNCTION "events___drop_table_x_____notify"()`,
+		`AFTER INSERT ON "Events""; DROP TABLE x; --"`,
+	} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("expected trigger SQL to contain %q, got:\n%s", want, sql)
+		}
+	}
+}
+
+func TestNotifierCloseTwice(t *testing.T) {
+	n := &Notifier{
+		listener: pq.NewListener("", time.Millisecond, time.Millisecond, nil),
+		signal:   make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+	if err := n.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := n.Close(); err != nil {
+		t.Errorf("expected a second Close to do nothing, got %v", err)
+	}
+}
+
+func TestNotifierCoalescesWakeups(t *testing.T) {
+	n := &Notifier{signal: make(chan struct{}, 1)}
+
+	// Several notifications before anyone waits collapse into one wake-up
+	n.wake()
+	n.wake()
+	n.wake()
+
+	select {
+	case <-n.Wait(context.Background()):
+	case <-time.After(time.Second):
+		t.Fatal("expected pending wake-up")
+	}
+
+	select {
+	case <-n.Wait(context.Background()):
+		t.Fatal("expected no second wake-up")
+	default:
+	}
+}
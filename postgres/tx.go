@@ -0,0 +1,45 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/shogotsuneto/go-simple-es-projector"
+	es "github.com/shogotsuneto/go-simple-eventstore"
+)
+
+// TxApplyFunc projects a batch (and persists 'next') inside tx.
+type TxApplyFunc func(ctx context.Context, tx *sql.Tx, batch []es.Envelope, next es.Cursor) error
+
+// InTx returns a projector.ApplyFunc that runs fn in a transaction on db,
+// committing if fn succeeds and rolling back otherwise. This is the
+// "projection + checkpoint in one transaction" pattern as a helper.
+//
+// During Worker.DryRun (projector.IsDryRun) the transaction is always rolled back,
+// so fn runs against real data without persisting anything.
+func InTx(db *sql.DB, fn TxApplyFunc) projector.ApplyFunc {
+	return func(ctx context.Context, batch []es.Envelope, next es.Cursor) error {
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction: %w", err)
+		}
+
+		if err := fn(ctx, tx, batch, next); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+
+		if projector.IsDryRun(ctx) {
+			if err := tx.Rollback(); err != nil {
+				return fmt.Errorf("failed to roll back dry run: %w", err)
+			}
+			return nil
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		return nil
+	}
+}
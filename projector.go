@@ -1,7 +1,9 @@
 package projector
 
 import (
+	"bytes"
 	"context"
+	"sync"
 	"time"
 
 	es "github.com/shogotsuneto/go-simple-eventstore"
@@ -9,22 +11,57 @@ import (
 
 // ApplyFunc is implemented by users to project events AND persist the 'next' cursor.
 // Apply must be idempotent; return an error to have the worker stop/retry.
+// The batch may be empty when a Filter, Deduper or StreamValidator dropped every fetched
+// event; persist 'next' anyway.
 type ApplyFunc func(ctx context.Context, batch []es.Envelope, next es.Cursor) error
 
 // Worker repeatedly pulls events from an event source and invokes user-provided projection logic.
 // Users fully own where/how they store the checkpoint (cursor) and whether to make
 // projection + checkpoint atomic (e.g., a DB transaction).
 type Worker struct {
-	Source    es.Consumer                 // event source (Postgres, DynamoDB Streams, Kafka…)
-	Apply     ApplyFunc                   // user projection + checkpoint
-	Start     es.Cursor                   // starting cursor (user loads from their store)
-	BatchSize int                         // default: 256
-	IdleSleep time.Duration               // default: 500ms between empty polls
-	Logger    func(msg string, kv ...any) // optional, nil-safe
+	Source     es.Consumer                 // event source (Postgres, DynamoDB Streams, Kafka…)
+	Apply      ApplyFunc                   // user projection + checkpoint
+	Middleware []Middleware                // optional; wraps Apply, first is outermost (see Chain)
+	Filter     Filter                      // optional; drops events before Apply (the cursor still advances)
+	Deduper    Deduper                     // optional; drops already-applied events before Apply
+	Validator  *StreamValidator            // optional; checks per-stream versions are contiguous
+	Reorder    *ReorderBuffer              // optional; restores per-stream order for unordered sources
+	Breaker    *CircuitBreaker             // optional; pauses fetching while Apply keeps failing
+	Start      es.Cursor                   // starting cursor (user loads from their store)
+	BatchSize  int                         // default: 256
+	BatchSizer BatchSizer                  // optional; adapts the fetch limit per batch (e.g. *AdaptiveBatchSize)
+	IdleSleep  time.Duration               // default: 500ms between empty polls
+	RateLimit  *RateLimit                  // optional; throttles events/batches per second before Apply
+	Poll       PollStrategy                // optional; overrides IdleSleep (e.g. ExponentialPoll)
+	Notifier   Notifier                    // optional; wakes an idle worker before the poll delay elapses
+	StopAtEnd  bool                        // optional; Run returns nil once caught up (first empty fetch) instead of idling
+	Logger     func(msg string, kv ...any) // optional, nil-safe
+	Hooks      Hooks                       // optional callbacks; nil fields are ignored
+	Clock      Clock                       // optional; defaults to the real clock
+
+	Idempotency *IdempotencyCheck // debug/test only; re-applies batches to verify idempotency
+
+	mu     sync.Mutex
+	status Status
+}
+
+// runState holds the state of one Run invocation
+type runState struct {
+	apply      ApplyFunc // Apply wrapped in Middleware
+	batchSize  int
+	poll       PollStrategy
+	clock      Clock
+	cursor     es.Cursor // fetch position
+	committed  es.Cursor // last cursor passed to Apply and committed to the source
+	emptyPolls int
+	prev       *delivery // last applied batch, for IdempotencyCheck.ReplayPrevious
+	throughput throughputMeter
 }
 
 // Run pulls events and calls Apply with 'next' cursor after each batch.
 // Flow: Fetch -> Apply (user persists data+cursor) -> Commit (source) -> advance.
+// It runs until ctx is done or a batch fails, or, with StopAtEnd, returns nil once
+// caught up.
 func (w *Worker) Run(ctx context.Context) error {
 	// Set defaults
 	batchSize := w.BatchSize
@@ -37,7 +74,22 @@ func (w *Worker) Run(ctx context.Context) error {
 		idleSleep = 500 * time.Millisecond
 	}
 
-	cursor := w.Start
+	poll := w.Poll
+	if poll == nil {
+		poll = FixedPoll(idleSleep)
+	}
+
+	r := &runState{
+		apply:     Chain(w.Middleware...)(w.Apply),
+		batchSize: batchSize,
+		poll:      poll,
+		clock:     w.clock(),
+		cursor:    w.Start,
+		committed: w.Start,
+	}
+
+	w.updateStatus(func(s *Status) { *s = Status{Running: true, Cursor: r.cursor, BatchSize: batchSize} })
+	defer w.updateStatus(func(s *Status) { s.Running = false })
 
 	w.logf("worker starting", "batchSize", batchSize, "idleSleep", idleSleep)
 
@@ -50,49 +102,250 @@ func (w *Worker) Run(ctx context.Context) error {
 		default:
 		}
 
+		if w.Breaker != nil {
+			if err := w.waitBreaker(ctx, r); err != nil {
+				return err
+			}
+		}
+
+		limit := r.batchSize
+		if w.BatchSizer != nil {
+			limit = w.BatchSizer.Limit()
+			w.updateStatus(func(s *Status) { s.BatchSize = limit })
+		}
+
 		// Fetch batch from source
-		batch, next, err := w.Source.Fetch(ctx, cursor, batchSize)
+		batch, next, err := w.fetch(ctx, r.cursor, limit)
 		if err != nil {
 			w.logf("fetch error", "error", err)
-			return err
+			return newError(PhaseFetch, r.cursor, nil, nil, err)
 		}
 
-		// If no events, sleep and continue
-		if len(batch) == 0 {
-			w.logf("no events fetched, sleeping", "idleSleep", idleSleep)
+		fetched := len(batch)
+		if fetched > 0 {
+			r.emptyPolls = 0
+			w.logf("fetched batch", "eventCount", fetched, "batchSize", limit)
+		}
 
-			select {
-			case <-ctx.Done():
-				w.logf("worker stopped due to context cancellation during idle sleep")
-				return ctx.Err()
-			case <-time.After(idleSleep):
-				// Continue to next iteration
+		if w.Deduper != nil && fetched > 0 {
+			kept, err := w.Deduper.Filter(ctx, batch)
+			if err != nil {
+				w.logf("dedupe error", "error", err)
+				return newError(PhaseDedupe, r.committed, next, batch, err)
+			}
+			batch = kept
+			if dropped := fetched - len(batch); dropped > 0 {
+				w.logf("dropped duplicate events", "duplicateCount", dropped)
 			}
-			continue
 		}
 
-		w.logf("fetched batch", "eventCount", len(batch))
+		commit := next
+		if w.Reorder != nil {
+			if fetched == 0 {
+				next = nil // nothing new to track; just check for timeouts
+			} else {
+				r.cursor = next // keep fetching past held events
+			}
+
+			released, c, err := w.Reorder.push(ctx, batch, next, r.clock.Now())
+			if err != nil {
+				w.logf("reorder error", "error", err)
+				return newError(PhaseReorder, r.committed, next, batch, err)
+			}
+			batch, commit = released, c
+			if commit == nil {
+				commit = r.committed
+			}
 
-		// Apply user projection logic with next cursor
-		err = w.Apply(ctx, batch, next)
-		if err != nil {
-			w.logf("apply error", "error", err, "eventCount", len(batch))
+			if held := w.Reorder.Held(); held > 0 {
+				w.logf("events held for reordering", "heldCount", held)
+			}
+			if len(batch) == 0 && bytes.Equal(commit, r.committed) {
+				if fetched > 0 {
+					continue // everything fetched is held; keep fetching
+				}
+				if w.StopAtEnd {
+					w.logf("caught up, stopping", "heldCount", w.Reorder.Held())
+					return nil
+				}
+				if err := w.idle(ctx, r); err != nil {
+					return err
+				}
+				continue
+			}
+		} else if fetched == 0 {
+			if w.StopAtEnd {
+				w.logf("caught up, stopping")
+				return nil
+			}
+			// If no events, sleep (or wait for a notification) and continue
+			if err := w.idle(ctx, r); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := w.process(ctx, r, batch, commit, fetched, limit); err != nil {
 			return err
 		}
+	}
+}
+
+// idle sleeps after an empty poll until the poll delay elapses or the Notifier fires
+func (w *Worker) idle(ctx context.Context, r *runState) error {
+	r.emptyPolls++
+	delay := r.poll.IdleDelay(r.emptyPolls)
+	w.logf("no events fetched, sleeping", "idleSleep", delay, "emptyPolls", r.emptyPolls)
 
-		w.logf("applied batch successfully", "eventCount", len(batch))
+	// A nil channel never fires, so without a Notifier this is a plain sleep
+	var wake <-chan struct{}
+	if w.Notifier != nil {
+		wake = w.Notifier.Wait(ctx)
+	}
+
+	timer := r.clock.NewTimer(delay)
+	select {
+	case <-ctx.Done():
+		timer.Stop()
+		w.logf("worker stopped due to context cancellation during idle sleep")
+		return ctx.Err()
+	case <-wake:
+		timer.Stop()
+		w.logf("woken up by notifier")
+	case <-timer.C():
+		// Continue to next iteration
+	}
+	return nil
+}
 
-		// Commit to source (may be no-op for some sources)
-		err = w.Source.Commit(ctx, next)
+// process applies a batch with cursor next and commits it to the source.
+// fetched and limit describe the Fetch the batch came from, for the BatchSizer.
+func (w *Worker) process(ctx context.Context, r *runState, batch []es.Envelope, next es.Cursor, fetched, limit int) error {
+	var versions map[string]int64
+	if w.Validator != nil {
+		valid, v, err := w.Validator.check(ctx, batch, w.reportAnomaly)
 		if err != nil {
-			w.logf("commit error", "error", err)
+			w.logf("stream validation error", "error", err)
+			return newError(PhaseValidate, r.committed, next, batch, err)
+		}
+		batch, versions = valid, v
+	}
+
+	// Filter after validation and reordering, which need to see every version of a stream
+	filtered := 0
+	if w.Filter != nil {
+		kept := w.Filter.apply(batch)
+		filtered = len(batch) - len(kept)
+		batch = kept
+		if filtered > 0 {
+			w.logf("filtered events", "filteredCount", filtered)
+		}
+	}
+
+	if w.RateLimit != nil {
+		if err := w.throttle(ctx, r, len(batch)); err != nil {
 			return err
 		}
+	}
+
+	// Apply user projection logic with next cursor
+	applyCtx := ctx
+	if versions != nil {
+		applyCtx = withPendingVersions(ctx, versions)
+	}
+	started := r.clock.Now()
+	err := w.apply(applyCtx, r, batch, next)
+	if w.BatchSizer != nil {
+		w.BatchSizer.Observe(fetched, limit, r.clock.Now().Sub(started), err)
+	}
+	if w.Breaker != nil && ctx.Err() == nil {
+		if t, ok := w.Breaker.record(err, r.clock.Now()); ok {
+			w.reportBreaker(t)
+		}
+	}
+	if err != nil {
+		w.logf("apply error", "error", err, "eventCount", len(batch))
+		return newError(PhaseApply, r.committed, next, batch, err)
+	}
+
+	w.logf("applied batch successfully", "eventCount", len(batch))
+
+	if w.Deduper != nil {
+		if err := w.Deduper.Mark(ctx, batch); err != nil {
+			w.logf("dedupe error", "error", err)
+			return newError(PhaseDedupe, r.committed, next, batch, err)
+		}
+	}
+	if w.Validator != nil {
+		if err := w.Validator.save(ctx, versions); err != nil {
+			w.logf("stream validation error", "error", err)
+			return newError(PhaseValidate, r.committed, next, batch, err)
+		}
+	}
+
+	if w.Idempotency != nil {
+		cur := delivery{batch: batch, next: next, committed: r.committed}
+		if err := w.verifyIdempotent(applyCtx, r, cur); err != nil {
+			w.logf("idempotency check error", "error", err)
+			return newError(PhaseVerify, r.committed, next, batch, err)
+		}
+		r.prev = &cur
+	}
+
+	// Commit to source (may be no-op for some sources)
+	if err := w.commit(ctx, r, batch, next); err != nil {
+		w.logf("commit error", "error", err)
+		return newError(PhaseCommit, r.committed, next, batch, err)
+	}
+
+	// Advance cursor
+	r.committed = next
+	if w.Reorder == nil {
+		r.cursor = next
+	}
+	now := r.clock.Now()
+	throughput := r.throughput.observe(now, len(batch))
+	w.updateStatus(func(s *Status) {
+		s.Cursor = next
+		s.Batches++
+		s.Events += int64(len(batch))
+		s.Filtered += int64(filtered)
+		s.Throughput = throughput
+		s.LastBatchAt = now
+	})
+
+	w.logf("batch processed", "cursorAdvanced", true)
+	return nil
+}
+
+// fetch, apply and commit call the source and Apply, recovering panics into a *PanicError
+// so a faulty batch stops the worker like any other error instead of crashing the process.
 
-		// Advance cursor
-		cursor = next
+func (w *Worker) fetch(ctx context.Context, cursor es.Cursor, limit int) (batch []es.Envelope, next es.Cursor, err error) {
+	defer recoverPanic(PhaseFetch, cursor, nil, nil, &err)
+	return w.Source.Fetch(ctx, cursor, limit)
+}
+
+func (w *Worker) apply(ctx context.Context, r *runState, batch []es.Envelope, next es.Cursor) error {
+	return w.applyAt(ctx, r, r.committed, batch, next)
+}
+
+// applyAt calls Apply (with Middleware) as if committed were the last committed cursor
+func (w *Worker) applyAt(ctx context.Context, r *runState, committed es.Cursor, batch []es.Envelope, next es.Cursor) (err error) {
+	defer recoverPanic(PhaseApply, committed, next, batch, &err)
+	return r.apply(withClock(withCommittedCursor(ctx, committed), r.clock), batch, next)
+}
+
+func (w *Worker) commit(ctx context.Context, r *runState, batch []es.Envelope, next es.Cursor) (err error) {
+	defer recoverPanic(PhaseCommit, r.committed, next, batch, &err)
+	return w.Source.Commit(ctx, next)
+}
 
-		w.logf("batch processed", "cursorAdvanced", true)
+// reportAnomaly logs a stream anomaly and forwards it to the hook
+func (w *Worker) reportAnomaly(a StreamAnomaly) {
+	w.logf("stream anomaly", "kind", a.Kind, "streamID", a.StreamID, "eventID", a.EventID, "expected", a.Expected, "got", a.Got)
+	if w.Hooks.OnStreamAnomaly != nil {
+		w.Hooks.OnStreamAnomaly(a)
 	}
 }
 
@@ -3,6 +3,7 @@ package projector
 import (
 	"context"
 	"errors"
+	"sync"
 	"testing"
 	"time"
 
@@ -11,6 +12,7 @@ import (
 
 // fakeConsumer implements es.Consumer for testing
 type fakeConsumer struct {
+	mu          sync.Mutex      // guards batches for tests that append while running
 	batches     [][]es.Envelope // pre-scripted batches to return
 	cursors     []es.Cursor     // corresponding cursors for each batch
 	batchIndex  int             // current batch index
@@ -35,6 +37,8 @@ func newFakeConsumer() *fakeConsumer {
 }
 
 func (f *fakeConsumer) AddBatch(batch []es.Envelope, cursor es.Cursor) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
 	f.batches = append(f.batches, batch)
 	f.cursors = append(f.cursors, cursor)
 }
@@ -48,6 +52,8 @@ func (f *fakeConsumer) SetCommitError(err error) {
 }
 
 func (f *fakeConsumer) Fetch(ctx context.Context, cursor es.Cursor, limit int) ([]es.Envelope, es.Cursor, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
 	f.fetchCalls = append(f.fetchCalls, fetchCall{cursor: cursor, limit: limit})
 
 	if f.fetchErr != nil {
@@ -201,7 +207,7 @@ func TestWorkerFetchError(t *testing.T) {
 	ctx := context.Background()
 	err := worker.Run(ctx)
 
-	if err != expectedErr {
+	if !errors.Is(err, expectedErr) {
 		t.Errorf("expected fetch error %v, got %v", expectedErr, err)
 	}
 }
@@ -225,7 +231,7 @@ func TestWorkerApplyError(t *testing.T) {
 	ctx := context.Background()
 	err := worker.Run(ctx)
 
-	if err != expectedErr {
+	if !errors.Is(err, expectedErr) {
 		t.Errorf("expected apply error %v, got %v", expectedErr, err)
 	}
 
@@ -257,7 +263,7 @@ func TestWorkerCommitError(t *testing.T) {
 	ctx := context.Background()
 	err := worker.Run(ctx)
 
-	if err != expectedErr {
+	if !errors.Is(err, expectedErr) {
 		t.Errorf("expected commit error %v, got %v", expectedErr, err)
 	}
 
@@ -380,3 +386,82 @@ type logEntry struct {
 	msg string
 	kv  []any
 }
+
+// fakeNotifier implements Notifier for testing
+type fakeNotifier struct {
+	signal chan struct{}
+}
+
+func (n *fakeNotifier) Wait(ctx context.Context) <-chan struct{} {
+	return n.signal
+}
+
+func TestWorkerNotifierWakesIdleWorker(t *testing.T) {
+	consumer := newFakeConsumer()
+	notifier := &fakeNotifier{signal: make(chan struct{}, 1)}
+
+	applied := make(chan struct{}, 1)
+	worker := &Worker{
+		Source:    consumer,
+		Start:     es.Cursor("start"),
+		IdleSleep: time.Hour, // Only a notification can wake the worker in time
+		Notifier:  notifier,
+		Apply: func(ctx context.Context, batch []es.Envelope, next es.Cursor) error {
+			applied <- struct{}{}
+			return nil
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() { done <- worker.Run(ctx) }()
+
+	// Let the worker go idle, then append an event and notify
+	time.Sleep(25 * time.Millisecond)
+	consumer.AddBatch([]es.Envelope{createTestEvent("1", "event1")}, es.Cursor("cursor1"))
+	notifier.signal <- struct{}{}
+
+	select {
+	case <-applied:
+	case <-ctx.Done():
+		t.Fatal("expected notification to wake the worker before IdleSleep elapsed")
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestWorkerStopAtEnd(t *testing.T) {
+	consumer := newFakeConsumer()
+	consumer.AddBatch([]es.Envelope{createTestEvent("1", "event1")}, es.Cursor("cursor1"))
+	consumer.AddBatch([]es.Envelope{createTestEvent("2", "event2")}, es.Cursor("cursor2"))
+
+	applied := 0
+	worker := &Worker{
+		Source:    consumer,
+		Start:     es.Cursor("start"),
+		IdleSleep: time.Hour, // Run must return without sleeping
+		StopAtEnd: true,
+		Apply: func(ctx context.Context, batch []es.Envelope, next es.Cursor) error {
+			applied += len(batch)
+			return nil
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if err := worker.Run(ctx); err != nil {
+		t.Fatalf("expected nil once caught up, got %v", err)
+	}
+	if applied != 2 {
+		t.Errorf("expected 2 applied events, got %d", applied)
+	}
+	if st := worker.Status(); st.Running || string(st.Cursor) != "cursor2" {
+		t.Errorf("expected stopped worker at cursor2, got %+v", st)
+	}
+}
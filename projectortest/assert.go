@@ -0,0 +1,100 @@
+package projectortest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shogotsuneto/go-simple-es-projector"
+	es "github.com/shogotsuneto/go-simple-eventstore"
+)
+
+// NewEvent returns an envelope with the given event ID and type, for use in scripts.
+func NewEvent(id, eventType string) es.Envelope {
+	return es.Envelope{Event: es.Event{ID: id, Type: eventType}}
+}
+
+// Drainer is a source that can tell when it has nothing left to deliver, such as Consumer.
+type Drainer interface {
+	Drained() <-chan struct{}
+}
+
+// RunUntilDrained runs w until c has nothing left to deliver and returns the error from Run.
+// A worker stopped because c was drained returns nil. The run is bounded by timeout.
+func RunUntilDrained(t testing.TB, w *projector.Worker, c Drainer, timeout time.Duration) error {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() { done <- w.Run(ctx) }()
+
+	select {
+	case err := <-done:
+		return err
+	case <-c.Drained():
+		cancel()
+		if err := <-done; !errors.Is(err, context.Canceled) {
+			return err
+		}
+		return nil
+	case <-ctx.Done():
+		t.Fatalf("worker did not drain the consumer within %v", timeout)
+		return <-done
+	}
+}
+
+// AssertCommitted fails t unless c's Commit calls match want, in order.
+func AssertCommitted(t testing.TB, c *Consumer, want ...es.Cursor) {
+	t.Helper()
+	assertCursors(t, "committed", c.Commits(), want)
+}
+
+// AssertAppliedCursors fails t unless r's successful calls received want as 'next', in order.
+func AssertAppliedCursors(t testing.TB, r *Recorder, want ...es.Cursor) {
+	t.Helper()
+	assertCursors(t, "applied", r.Cursors(), want)
+}
+
+// AssertAppliedOnce fails t unless every event ID in ids was successfully applied exactly
+// once and no other event was applied.
+func AssertAppliedOnce(t testing.TB, r *Recorder, ids ...string) {
+	t.Helper()
+
+	counts := map[string]int{}
+	for _, ev := range r.Events() {
+		counts[ev.Event.ID]++
+	}
+
+	for _, id := range ids {
+		switch n := counts[id]; n {
+		case 1:
+		case 0:
+			t.Errorf("event %q was never applied", id)
+		default:
+			t.Errorf("event %q was applied %d times", id, n)
+		}
+		delete(counts, id)
+	}
+	for id, n := range counts {
+		t.Errorf("unexpected event %q applied %d time(s)", id, n)
+	}
+}
+
+// assertCursors compares two cursor sequences
+func assertCursors(t testing.TB, what string, got, want []es.Cursor) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Errorf("expected %d %s cursors %q, got %d: %q", len(want), what, want, len(got), got)
+		return
+	}
+	for i := range want {
+		if !bytes.Equal(got[i], want[i]) {
+			t.Errorf("%s cursor %d: expected %q, got %q", what, i, want[i], got[i])
+		}
+	}
+}
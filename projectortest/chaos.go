@@ -0,0 +1,163 @@
+package projectortest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/shogotsuneto/go-simple-es-projector"
+	es "github.com/shogotsuneto/go-simple-eventstore"
+)
+
+// ErrChaos is the error returned by faults injected by Chaos.
+var ErrChaos = errors.New("projectortest: injected fault")
+
+// Compile-time interface compliance check
+var _ es.Consumer = (*Chaos)(nil)
+
+// Chaos is an es.Consumer decorator that injects faults into Source, driven by a
+// seeded RNG so a failing run can be reproduced from its Seed.
+//
+// Every fault stays within the at-least-once contract: events may be delivered again,
+// out of order within a batch, or late, but never skipped. A projection that survives
+// Chaos survives any conforming source. Rates are probabilities in [0, 1] per call.
+type Chaos struct {
+	Source es.Consumer
+	Seed   uint64
+
+	FetchErrorRate  float64 // Fetch fails with ErrChaos without reaching Source
+	CommitErrorRate float64 // Commit fails with ErrChaos without reaching Source
+	DuplicateRate   float64 // the previous batch is delivered again
+	ReorderRate     float64 // events within a batch are shuffled
+	TruncateRate    float64 // only a prefix of the batch is delivered; the cursor does not advance
+	RewindRate      float64 // the batch is delivered with an older cursor, causing redelivery
+	LatencyRate     float64 // Fetch is delayed by Latency
+	Latency         time.Duration
+
+	Clock projector.Clock // used for latency; nil uses the real clock
+
+	mu      sync.Mutex
+	rng     *rand.Rand
+	last    *delivery   // previous batch returned, for duplicates
+	history []es.Cursor // cursors fetched from so far, for rewinds
+	stats   ChaosStats
+}
+
+// ChaosStats counts the faults a Chaos has injected.
+type ChaosStats struct {
+	Fetches, Commits                         int
+	FetchErrors, CommitErrors                int
+	Duplicates, Reorders, Truncates, Rewinds int
+	LatencySpikes                            int
+}
+
+// Total returns the number of injected faults of any kind.
+func (s ChaosStats) Total() int {
+	return s.FetchErrors + s.CommitErrors + s.Duplicates + s.Reorders + s.Truncates + s.Rewinds + s.LatencySpikes
+}
+
+// delivery is a batch previously returned by Chaos
+type delivery struct {
+	batch []es.Envelope
+	next  es.Cursor
+}
+
+// Fetch implements es.Consumer.
+func (c *Chaos) Fetch(ctx context.Context, cursor es.Cursor, limit int) ([]es.Envelope, es.Cursor, error) {
+	c.mu.Lock()
+	c.stats.Fetches++
+	spike := c.roll(c.LatencyRate)
+	if spike {
+		c.stats.LatencySpikes++
+	}
+	if c.roll(c.FetchErrorRate) {
+		c.stats.FetchErrors++
+		c.mu.Unlock()
+		return nil, nil, ErrChaos
+	}
+	if c.last != nil && bytes.Equal(cursor, c.last.next) && len(c.last.batch) > 0 && c.roll(c.DuplicateRate) {
+		c.stats.Duplicates++
+		batch, next := c.last.batch, c.last.next
+		c.mu.Unlock()
+		return batch, next, nil
+	}
+	c.mu.Unlock()
+
+	if spike && c.Latency > 0 {
+		if err := sleep(ctx, c.Clock, c.Latency); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	batch, next, err := c.Source.Fetch(ctx, cursor, limit)
+	if err != nil || len(batch) == 0 {
+		return batch, next, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	batch = append([]es.Envelope(nil), batch...)
+	if len(batch) > 1 && c.roll(c.ReorderRate) {
+		c.stats.Reorders++
+		c.rand().Shuffle(len(batch), func(i, j int) { batch[i], batch[j] = batch[j], batch[i] })
+	}
+	if c.roll(c.TruncateRate) {
+		c.stats.Truncates++
+		batch = batch[:1+c.rand().IntN(len(batch))]
+		next = cursor
+	} else if len(c.history) > 0 && c.roll(c.RewindRate) {
+		c.stats.Rewinds++
+		next = c.history[c.rand().IntN(len(c.history))]
+	}
+
+	c.history = append(c.history, cursor)
+	c.last = &delivery{batch: batch, next: next}
+	return batch, next, nil
+}
+
+// Commit implements es.Consumer.
+func (c *Chaos) Commit(ctx context.Context, cursor es.Cursor) error {
+	c.mu.Lock()
+	c.stats.Commits++
+	if c.roll(c.CommitErrorRate) {
+		c.stats.CommitErrors++
+		c.mu.Unlock()
+		return ErrChaos
+	}
+	c.mu.Unlock()
+
+	return c.Source.Commit(ctx, cursor)
+}
+
+// Drained forwards to Source if it is a Drainer, so RunUntilDrained works through Chaos.
+// Otherwise the returned channel never fires.
+func (c *Chaos) Drained() <-chan struct{} {
+	if d, ok := c.Source.(Drainer); ok {
+		return d.Drained()
+	}
+	return nil
+}
+
+// Stats returns the faults injected so far.
+func (c *Chaos) Stats() ChaosStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
+
+// rand lazily seeds the RNG; c.mu must be held
+func (c *Chaos) rand() *rand.Rand {
+	if c.rng == nil {
+		c.rng = rand.New(rand.NewPCG(c.Seed, c.Seed))
+	}
+	return c.rng
+}
+
+// roll returns true with probability p; c.mu must be held
+func (c *Chaos) roll(p float64) bool {
+	return p > 0 && c.rand().Float64() < p
+}
@@ -0,0 +1,126 @@
+package projectortest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/shogotsuneto/go-simple-es-projector"
+	es "github.com/shogotsuneto/go-simple-eventstore"
+)
+
+func chaosEvents(n int) []es.Envelope {
+	events := make([]es.Envelope, n)
+	for i := range events {
+		events[i] = NewEvent(fmt.Sprint(i+1), "test.event")
+	}
+	return events
+}
+
+func TestChaosIsDeterministicForSeed(t *testing.T) {
+	run := func() ([]string, ChaosStats) {
+		c := &Chaos{
+			Source:        &logConsumer{events: chaosEvents(20), drained: make(chan struct{})},
+			Seed:          42,
+			DuplicateRate: 0.3,
+			ReorderRate:   0.3,
+			TruncateRate:  0.3,
+			RewindRate:    0.3,
+		}
+
+		var ids []string
+		var cursor es.Cursor
+		for i := 0; i < 30; i++ {
+			batch, next, err := c.Fetch(context.Background(), cursor, 4)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, ev := range batch {
+				ids = append(ids, ev.Event.ID)
+			}
+			cursor = next
+		}
+		return ids, c.Stats()
+	}
+
+	ids1, stats1 := run()
+	ids2, stats2 := run()
+
+	if fmt.Sprint(ids1) != fmt.Sprint(ids2) || stats1 != stats2 {
+		t.Errorf("expected identical runs for the same seed:\n%v %+v\n%v %+v", ids1, stats1, ids2, stats2)
+	}
+	if stats1.Total() == 0 {
+		t.Error("expected some faults to be injected")
+	}
+}
+
+func TestChaosFetchAndCommitErrors(t *testing.T) {
+	c := &Chaos{Source: NewConsumer(), FetchErrorRate: 1, CommitErrorRate: 1}
+
+	if _, _, err := c.Fetch(context.Background(), nil, 1); !errors.Is(err, ErrChaos) {
+		t.Errorf("expected injected fetch error, got %v", err)
+	}
+	if err := c.Commit(context.Background(), nil); !errors.Is(err, ErrChaos) {
+		t.Errorf("expected injected commit error, got %v", err)
+	}
+	if s := c.Stats(); s.FetchErrors != 1 || s.CommitErrors != 1 {
+		t.Errorf("unexpected stats: %+v", s)
+	}
+}
+
+func TestChaosWorkerConvergesWithIdempotentProjection(t *testing.T) {
+	events := chaosEvents(50)
+	src := &logConsumer{events: events, drained: make(chan struct{})}
+	chaos := &Chaos{
+		Source:          src,
+		Seed:            7,
+		FetchErrorRate:  0.1,
+		CommitErrorRate: 0.1,
+		DuplicateRate:   0.2,
+		ReorderRate:     0.2,
+		TruncateRate:    0.2,
+		RewindRate:      0.1,
+		LatencyRate:     0.1,
+		Latency:         time.Millisecond,
+	}
+
+	// Idempotent projection: the set of applied event IDs, plus the checkpoint
+	applied := map[string]bool{}
+	var checkpoint es.Cursor
+	apply := func(ctx context.Context, batch []es.Envelope, next es.Cursor) error {
+		for _, ev := range batch {
+			applied[ev.Event.ID] = true
+		}
+		checkpoint = next
+		return nil
+	}
+
+	// Restart from the stored checkpoint after every injected failure
+	for restarts := 0; ; restarts++ {
+		if restarts > 1000 {
+			t.Fatal("worker did not converge")
+		}
+		w := &projector.Worker{Source: chaos, Start: checkpoint, Apply: apply, BatchSize: 4, IdleSleep: time.Millisecond}
+		err := RunUntilDrained(t, w, chaos, 5*time.Second)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, ErrChaos) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	for _, ev := range events {
+		if !applied[ev.Event.ID] {
+			t.Errorf("event %s was never applied", ev.Event.ID)
+		}
+	}
+	if position(checkpoint) != len(events) {
+		t.Errorf("expected checkpoint at %d, got %d", len(events), position(checkpoint))
+	}
+	if chaos.Stats().Total() == 0 {
+		t.Error("expected faults to be injected")
+	}
+}
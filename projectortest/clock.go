@@ -0,0 +1,138 @@
+package projectortest
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/shogotsuneto/go-simple-es-projector"
+)
+
+// Compile-time interface compliance check
+var _ projector.Clock = (*FakeClock)(nil)
+
+// FakeClock is a projector.Clock whose time only moves when the test advances it.
+// Timers fire synchronously inside Advance, so idle sleeps, backoffs and timeouts
+// complete instantly and in a deterministic order.
+type FakeClock struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	now     time.Time
+	waiters []*fakeTimer
+}
+
+// NewFakeClock returns a FakeClock set to start.
+func NewFakeClock(start time.Time) *FakeClock {
+	c := &FakeClock{now: start}
+	c.cond = sync.NewCond(&c.mu)
+	return c
+}
+
+// Now implements projector.Clock.
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// After implements projector.Clock.
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	return c.NewTimer(d).C()
+}
+
+// NewTimer implements projector.Clock.
+func (c *FakeClock) NewTimer(d time.Duration) projector.Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t := &fakeTimer{clock: c, at: c.now.Add(d), ch: make(chan time.Time, 1)}
+	if d <= 0 {
+		t.ch <- c.now
+		return t
+	}
+	c.waiters = append(c.waiters, t)
+	c.cond.Broadcast()
+	return t
+}
+
+// Advance moves the clock forward by d, firing every timer that becomes due.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.advanceTo(c.now.Add(d))
+}
+
+// AdvanceNext moves the clock to the earliest pending timer, fires it and returns
+// how far the clock moved. It blocks until a timer is pending.
+func (c *FakeClock) AdvanceNext() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for len(c.waiters) == 0 {
+		c.cond.Wait()
+	}
+	sort.SliceStable(c.waiters, func(i, j int) bool { return c.waiters[i].at.Before(c.waiters[j].at) })
+
+	d := c.waiters[0].at.Sub(c.now)
+	c.advanceTo(c.waiters[0].at)
+	return d
+}
+
+// BlockUntil blocks until at least n timers are pending. Use it to wait for the
+// code under test to go to sleep before advancing the clock.
+func (c *FakeClock) BlockUntil(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for len(c.waiters) < n {
+		c.cond.Wait()
+	}
+}
+
+// Pending returns the number of timers that have not fired or been stopped.
+func (c *FakeClock) Pending() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.waiters)
+}
+
+// advanceTo sets now and fires due timers; c.mu must be held
+func (c *FakeClock) advanceTo(now time.Time) {
+	if now.After(c.now) {
+		c.now = now
+	}
+
+	remaining := c.waiters[:0]
+	for _, t := range c.waiters {
+		if t.at.After(c.now) {
+			remaining = append(remaining, t)
+			continue
+		}
+		t.ch <- c.now
+	}
+	c.waiters = remaining
+}
+
+// remove drops t from the pending timers, reporting whether it was pending
+func (c *FakeClock) remove(t *fakeTimer) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, w := range c.waiters {
+		if w == t {
+			c.waiters = append(c.waiters[:i], c.waiters[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// fakeTimer implements projector.Timer for FakeClock
+type fakeTimer struct {
+	clock *FakeClock
+	at    time.Time
+	ch    chan time.Time
+}
+
+func (t *fakeTimer) C() <-chan time.Time { return t.ch }
+func (t *fakeTimer) Stop() bool          { return t.clock.remove(t) }
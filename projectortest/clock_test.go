@@ -0,0 +1,108 @@
+package projectortest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shogotsuneto/go-simple-es-projector"
+	es "github.com/shogotsuneto/go-simple-eventstore"
+)
+
+func TestFakeClockAdvanceFiresDueTimers(t *testing.T) {
+	c := NewFakeClock(time.Unix(0, 0))
+
+	short := c.NewTimer(time.Second)
+	long := c.NewTimer(time.Minute)
+
+	c.Advance(30 * time.Second)
+
+	select {
+	case <-short.C():
+	default:
+		t.Fatal("expected 1s timer to fire after advancing 30s")
+	}
+	select {
+	case <-long.C():
+		t.Fatal("expected 1m timer not to fire yet")
+	default:
+	}
+	if c.Pending() != 1 {
+		t.Errorf("expected 1 pending timer, got %d", c.Pending())
+	}
+}
+
+func TestFakeClockAdvanceNext(t *testing.T) {
+	c := NewFakeClock(time.Unix(0, 0))
+
+	ch := c.After(5 * time.Second)
+	_ = c.After(10 * time.Second)
+
+	if d := c.AdvanceNext(); d != 5*time.Second {
+		t.Errorf("expected to advance 5s, got %v", d)
+	}
+	if got := <-ch; !got.Equal(time.Unix(5, 0)) {
+		t.Errorf("expected timer to fire at t=5s, got %v", got)
+	}
+	if d := c.AdvanceNext(); d != 5*time.Second {
+		t.Errorf("expected to advance another 5s, got %v", d)
+	}
+}
+
+func TestFakeClockStop(t *testing.T) {
+	c := NewFakeClock(time.Unix(0, 0))
+
+	timer := c.NewTimer(time.Second)
+	if !timer.Stop() {
+		t.Error("expected Stop to report the timer was pending")
+	}
+	if timer.Stop() {
+		t.Error("expected second Stop to report false")
+	}
+
+	c.Advance(time.Minute)
+	select {
+	case <-timer.C():
+		t.Fatal("expected stopped timer not to fire")
+	default:
+	}
+}
+
+func TestFakeClockControlsMiddleware(t *testing.T) {
+	c := NewFakeClock(time.Unix(0, 0))
+
+	var took []any
+	logf := func(msg string, kv ...any) {
+		if msg == "apply failed" {
+			took = append(took, kv[3])
+		}
+	}
+	w := &projector.Worker{
+		Source:     NewConsumer().AddBatch(es.Cursor("cursor1"), NewEvent("1", "test.event")),
+		Clock:      c,
+		Middleware: []projector.Middleware{projector.LogEvents(logf), projector.Timeout(time.Minute)},
+		Apply: func(ctx context.Context, batch []es.Envelope, next es.Cursor) error {
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- w.Run(context.Background()) }()
+
+	c.BlockUntil(1) // the Timeout timer
+	c.Advance(time.Minute)
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected Timeout to follow the fake clock")
+	}
+	if len(took) != 1 || took[0] != time.Minute {
+		t.Errorf("expected LogEvents to time the batch on the fake clock, got %v", took)
+	}
+}
@@ -0,0 +1,175 @@
+package projectortest
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/shogotsuneto/go-simple-es-projector"
+	es "github.com/shogotsuneto/go-simple-eventstore"
+)
+
+// Compile-time interface compliance check
+var _ es.Consumer = (*Consumer)(nil)
+
+// Step is one scripted response of Consumer.Fetch.
+type Step struct {
+	Batch   []es.Envelope // events to return
+	Next    es.Cursor     // cursor to return; nil returns the requested cursor unchanged
+	Err     error         // error to return instead of the batch
+	Latency time.Duration // extra delay before returning (on top of Consumer.Latency)
+}
+
+// FetchCall records one call to Consumer.Fetch.
+type FetchCall struct {
+	Cursor es.Cursor
+	Limit  int
+}
+
+// Consumer is a scriptable in-memory es.Consumer for tests.
+//
+// Each Fetch consumes the next scripted Step; once the script is exhausted Fetch
+// returns an empty batch with the requested cursor, as a caught-up source would.
+// All calls are recorded. It is safe for concurrent use, so steps can be added
+// while a Worker is running.
+type Consumer struct {
+	Latency time.Duration   // delay applied to every Fetch
+	Clock   projector.Clock // used for latency; nil uses the real clock
+
+	mu          sync.Mutex
+	steps       []Step
+	commitErrs  []error
+	fetches     []FetchCall
+	commits     []es.Cursor
+	drained     chan struct{}
+	drainedOnce sync.Once
+}
+
+// NewConsumer returns a Consumer with the given script.
+func NewConsumer(steps ...Step) *Consumer {
+	return &Consumer{steps: steps, drained: make(chan struct{})}
+}
+
+// AddBatch scripts a Fetch that returns events and advances to next.
+func (c *Consumer) AddBatch(next es.Cursor, events ...es.Envelope) *Consumer {
+	return c.AddStep(Step{Batch: events, Next: next})
+}
+
+// AddEmpty scripts a Fetch that returns no events.
+func (c *Consumer) AddEmpty() *Consumer {
+	return c.AddStep(Step{})
+}
+
+// FailFetch scripts a Fetch that returns err.
+func (c *Consumer) FailFetch(err error) *Consumer {
+	return c.AddStep(Step{Err: err})
+}
+
+// AddStep appends a step to the script.
+func (c *Consumer) AddStep(s Step) *Consumer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.steps = append(c.steps, s)
+	return c
+}
+
+// FailCommit makes upcoming Commit calls return errs, one per call, in order.
+// A nil entry lets that call succeed.
+func (c *Consumer) FailCommit(errs ...error) *Consumer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.commitErrs = append(c.commitErrs, errs...)
+	return c
+}
+
+// Fetch implements es.Consumer.
+func (c *Consumer) Fetch(ctx context.Context, cursor es.Cursor, limit int) ([]es.Envelope, es.Cursor, error) {
+	c.mu.Lock()
+	c.fetches = append(c.fetches, FetchCall{Cursor: cursor, Limit: limit})
+
+	var step Step
+	if len(c.steps) > 0 {
+		step = c.steps[0]
+		c.steps = c.steps[1:]
+	} else {
+		c.drainedOnce.Do(func() { close(c.drainedChan()) })
+	}
+	latency := c.Latency + step.Latency
+	c.mu.Unlock()
+
+	if latency > 0 {
+		if err := sleep(ctx, c.Clock, latency); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if step.Err != nil {
+		return nil, nil, step.Err
+	}
+	next := step.Next
+	if next == nil {
+		next = cursor
+	}
+	return step.Batch, next, nil
+}
+
+// Commit implements es.Consumer.
+func (c *Consumer) Commit(ctx context.Context, cursor es.Cursor) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.commits = append(c.commits, cursor)
+	if len(c.commitErrs) > 0 {
+		err := c.commitErrs[0]
+		c.commitErrs = c.commitErrs[1:]
+		return err
+	}
+	return nil
+}
+
+// Drained returns a channel that is closed the first time Fetch is called after
+// the script has been fully consumed.
+func (c *Consumer) Drained() <-chan struct{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.drainedChan()
+}
+
+// Fetches returns a copy of all recorded Fetch calls.
+func (c *Consumer) Fetches() []FetchCall {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]FetchCall(nil), c.fetches...)
+}
+
+// Commits returns a copy of all cursors passed to Commit, in order.
+func (c *Consumer) Commits() []es.Cursor {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]es.Cursor(nil), c.commits...)
+}
+
+// drainedChan lazily creates the drained channel for zero-value Consumers; c.mu must be held
+func (c *Consumer) drainedChan() chan struct{} {
+	if c.drained == nil {
+		c.drained = make(chan struct{})
+	}
+	return c.drained
+}
+
+// sleep waits for d on clock (the real clock if nil), honoring ctx
+func sleep(ctx context.Context, clock projector.Clock, d time.Duration) error {
+	var after <-chan time.Time
+	if clock != nil {
+		after = clock.After(d)
+	} else {
+		after = time.After(d)
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-after:
+		return nil
+	}
+}
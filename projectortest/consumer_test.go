@@ -0,0 +1,149 @@
+package projectortest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shogotsuneto/go-simple-es-projector"
+	es "github.com/shogotsuneto/go-simple-eventstore"
+)
+
+func TestConsumerFollowsScript(t *testing.T) {
+	fetchErr := errors.New("fetch failed")
+	c := NewConsumer().
+		AddBatch(es.Cursor("c1"), NewEvent("1", "test.event")).
+		FailFetch(fetchErr).
+		AddEmpty()
+
+	ctx := context.Background()
+
+	batch, next, err := c.Fetch(ctx, es.Cursor("start"), 10)
+	if err != nil || len(batch) != 1 || string(next) != "c1" {
+		t.Fatalf("expected 1 event and cursor c1, got %d events, %q, %v", len(batch), next, err)
+	}
+
+	if _, _, err := c.Fetch(ctx, next, 10); err != fetchErr {
+		t.Fatalf("expected scripted fetch error, got %v", err)
+	}
+
+	batch, next, err = c.Fetch(ctx, es.Cursor("c1"), 10)
+	if err != nil || len(batch) != 0 || string(next) != "c1" {
+		t.Fatalf("expected empty batch at c1, got %d events, %q, %v", len(batch), next, err)
+	}
+
+	select {
+	case <-c.Drained():
+		t.Fatal("expected consumer not to be drained before the script is exhausted")
+	default:
+	}
+
+	// Past the end of the script: empty batches forever
+	if batch, _, _ := c.Fetch(ctx, es.Cursor("c1"), 5); len(batch) != 0 {
+		t.Errorf("expected empty batch after script, got %d events", len(batch))
+	}
+	select {
+	case <-c.Drained():
+	default:
+		t.Error("expected consumer to be drained")
+	}
+
+	fetches := c.Fetches()
+	if len(fetches) != 4 || fetches[3].Limit != 5 || string(fetches[3].Cursor) != "c1" {
+		t.Errorf("unexpected recorded fetches: %+v", fetches)
+	}
+}
+
+func TestConsumerCommitErrors(t *testing.T) {
+	commitErr := errors.New("commit failed")
+	c := NewConsumer().FailCommit(nil, commitErr)
+
+	ctx := context.Background()
+	if err := c.Commit(ctx, es.Cursor("a")); err != nil {
+		t.Errorf("expected first commit to succeed, got %v", err)
+	}
+	if err := c.Commit(ctx, es.Cursor("b")); err != commitErr {
+		t.Errorf("expected second commit to fail, got %v", err)
+	}
+	if err := c.Commit(ctx, es.Cursor("c")); err != nil {
+		t.Errorf("expected third commit to succeed, got %v", err)
+	}
+
+	AssertCommitted(t, c, es.Cursor("a"), es.Cursor("b"), es.Cursor("c"))
+}
+
+func TestConsumerLatencyUsesClock(t *testing.T) {
+	clock := NewFakeClock(time.Unix(0, 0))
+	c := NewConsumer(Step{Batch: []es.Envelope{NewEvent("1", "test.event")}, Latency: time.Minute})
+	c.Clock = clock
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _, _ = c.Fetch(context.Background(), nil, 1)
+	}()
+
+	if d := clock.AdvanceNext(); d != time.Minute {
+		t.Errorf("expected fetch to wait 1m, got %v", d)
+	}
+	<-done
+}
+
+func TestRecorderWithWorker(t *testing.T) {
+	applyErr := errors.New("apply failed")
+	c := NewConsumer().
+		AddBatch(es.Cursor("c1"), NewEvent("1", "test.event"), NewEvent("2", "test.event")).
+		AddBatch(es.Cursor("c2"), NewEvent("3", "test.event"))
+
+	var seen int
+	r := &Recorder{Fn: func(ctx context.Context, batch []es.Envelope, next es.Cursor) error {
+		seen += len(batch)
+		return nil
+	}}
+
+	w := &projector.Worker{Source: c, Start: es.Cursor("start"), Apply: r.Apply}
+	if err := RunUntilDrained(t, w, c, time.Second); err != nil {
+		t.Fatalf("expected worker to drain cleanly, got %v", err)
+	}
+
+	AssertAppliedOnce(t, r, "1", "2", "3")
+	AssertAppliedCursors(t, r, es.Cursor("c1"), es.Cursor("c2"))
+	AssertCommitted(t, c, es.Cursor("c1"), es.Cursor("c2"))
+	if seen != 3 {
+		t.Errorf("expected Fn to see 3 events, got %d", seen)
+	}
+
+	// A scripted failure stops the worker without calling Fn
+	c = NewConsumer().AddBatch(es.Cursor("c3"), NewEvent("4", "test.event"))
+	r.Fail(applyErr)
+	w = &projector.Worker{Source: c, Start: es.Cursor("c2"), Apply: r.Apply}
+	if err := RunUntilDrained(t, w, c, time.Second); !errors.Is(err, applyErr) {
+		t.Fatalf("expected scripted apply error, got %v", err)
+	}
+	if seen != 3 {
+		t.Errorf("expected Fn not to be called for a scripted failure, got %d events", seen)
+	}
+}
+
+func TestAssertAppliedOnceReportsDuplicates(t *testing.T) {
+	r := &Recorder{}
+	ctx := context.Background()
+	_ = r.Apply(ctx, []es.Envelope{NewEvent("1", "t"), NewEvent("2", "t")}, es.Cursor("c1"))
+	_ = r.Apply(ctx, []es.Envelope{NewEvent("2", "t")}, es.Cursor("c2"))
+
+	ft := &fakeTB{}
+	AssertAppliedOnce(ft, r, "1", "2")
+	if !ft.failed {
+		t.Error("expected AssertAppliedOnce to fail on a duplicate")
+	}
+}
+
+// fakeTB captures assertion failures instead of failing the test
+type fakeTB struct {
+	testing.TB
+	failed bool
+}
+
+func (f *fakeTB) Helper()                           {}
+func (f *fakeTB) Errorf(format string, args ...any) { f.failed = true }
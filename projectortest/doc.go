@@ -0,0 +1,3 @@
+// Package projectortest provides helpers for testing code built on the projector package
+// deterministically and without real sleeps.
+package projectortest
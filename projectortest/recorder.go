@@ -0,0 +1,92 @@
+package projectortest
+
+import (
+	"context"
+	"sync"
+
+	"github.com/shogotsuneto/go-simple-es-projector"
+	es "github.com/shogotsuneto/go-simple-eventstore"
+)
+
+// ApplyCall records one call to Recorder.Apply.
+type ApplyCall struct {
+	Batch []es.Envelope
+	Next  es.Cursor
+	Err   error // error returned to the worker
+}
+
+// Recorder is a projector.ApplyFunc that records every batch it receives.
+//
+// Set Fn to delegate to real projection logic; the call is recorded either way.
+// It is safe for concurrent use.
+type Recorder struct {
+	Fn projector.ApplyFunc // optional; called for every batch
+
+	mu    sync.Mutex
+	calls []ApplyCall
+	errs  []error
+}
+
+// Apply implements projector.ApplyFunc. Pass r.Apply as Worker.Apply.
+func (r *Recorder) Apply(ctx context.Context, batch []es.Envelope, next es.Cursor) error {
+	r.mu.Lock()
+	var err error
+	if len(r.errs) > 0 {
+		err = r.errs[0]
+		r.errs = r.errs[1:]
+	}
+	r.mu.Unlock()
+
+	if err == nil && r.Fn != nil {
+		err = r.Fn(ctx, batch, next)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, ApplyCall{Batch: batch, Next: next, Err: err})
+	return err
+}
+
+// Fail makes upcoming Apply calls return errs, one per call, in order, without calling Fn.
+// A nil entry lets that call through.
+func (r *Recorder) Fail(errs ...error) *Recorder {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.errs = append(r.errs, errs...)
+	return r
+}
+
+// Calls returns a copy of all recorded calls, including failed ones.
+func (r *Recorder) Calls() []ApplyCall {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]ApplyCall(nil), r.calls...)
+}
+
+// Events returns every event from successful calls, in the order applied.
+func (r *Recorder) Events() []es.Envelope {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var events []es.Envelope
+	for _, c := range r.calls {
+		if c.Err == nil {
+			events = append(events, c.Batch...)
+		}
+	}
+	return events
+}
+
+// Cursors returns the 'next' cursor of every successful call, in order.
+func (r *Recorder) Cursors() []es.Cursor {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var cursors []es.Cursor
+	for _, c := range r.calls {
+		if c.Err == nil {
+			cursors = append(cursors, c.Next)
+		}
+	}
+	return cursors
+}
@@ -0,0 +1,156 @@
+package projectortest
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shogotsuneto/go-simple-es-projector"
+	es "github.com/shogotsuneto/go-simple-eventstore"
+)
+
+// Scenario is a Given/When/Then projection test:
+// given these events, when the real Worker runs until it has caught up,
+// then the projection store satisfies Then and the stored checkpoint equals WantCheckpoint.
+//
+// S is your projection store (an in-memory map, a test database handle, ...).
+// Run executes the scenario twice: once with normal delivery, and once with every
+// batch delivered a second time, proving Apply is idempotent as the at-least-once
+// delivery contract requires.
+type Scenario[S any] struct {
+	Given     []es.Envelope // events in the source, in order
+	BatchSize int           // default: 2, so multi-batch behavior is exercised
+
+	New   func(t testing.TB) S              // returns a fresh, empty store for each run
+	Apply func(store S) projector.ApplyFunc // the projection under test
+	Then  func(t testing.TB, store S)       // assertions on the projected state
+
+	// Checkpoint reads the cursor the projection persisted; optional.
+	// When set, it must equal WantCheckpoint (default: Position(len(Given))).
+	Checkpoint     func(store S) es.Cursor
+	WantCheckpoint es.Cursor
+
+	Timeout time.Duration // per run; default: 5s
+}
+
+// Run executes the scenario as the subtests "once" and "replayed".
+func (s Scenario[S]) Run(t *testing.T) {
+	t.Helper()
+	t.Run("once", func(t *testing.T) { s.RunOnce(t) })
+	t.Run("replayed", func(t *testing.T) { s.RunReplayed(t) })
+}
+
+// RunOnce executes the scenario with every event delivered exactly once.
+func (s Scenario[S]) RunOnce(t testing.TB) {
+	t.Helper()
+	s.run(t, false)
+}
+
+// RunReplayed executes the scenario with every batch delivered twice.
+func (s Scenario[S]) RunReplayed(t testing.TB) {
+	t.Helper()
+	s.run(t, true)
+}
+
+// run executes one pass of the scenario
+func (s Scenario[S]) run(t testing.TB, replay bool) {
+	t.Helper()
+
+	batchSize := s.BatchSize
+	if batchSize <= 0 {
+		batchSize = 2
+	}
+	timeout := s.Timeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+
+	store := s.New(t)
+	src := &logConsumer{events: s.Given, replay: replay, drained: make(chan struct{})}
+	w := &projector.Worker{
+		Source:    src,
+		Apply:     s.Apply(store),
+		BatchSize: batchSize,
+		IdleSleep: time.Millisecond,
+	}
+
+	if err := RunUntilDrained(t, w, src, timeout); err != nil {
+		t.Fatalf("worker failed: %v", err)
+	}
+
+	if s.Then != nil {
+		s.Then(t, store)
+	}
+
+	if s.Checkpoint != nil {
+		want := s.WantCheckpoint
+		if want == nil {
+			want = Position(len(s.Given))
+		}
+		if got := s.Checkpoint(store); !bytes.Equal(got, want) {
+			t.Errorf("expected checkpoint %x, got %x", want, got)
+		}
+	}
+}
+
+// Position returns the cursor a Scenario source hands out after its first n events:
+// an 8-byte little-endian position, the same layout as go-simple-eventstore's postgres consumer.
+func Position(n int) es.Cursor {
+	cursor := make(es.Cursor, 8)
+	binary.LittleEndian.PutUint64(cursor, uint64(n))
+	return cursor
+}
+
+// position decodes a Position cursor; an empty cursor is the beginning
+func position(cursor es.Cursor) int {
+	if len(cursor) < 8 {
+		return 0
+	}
+	return int(binary.LittleEndian.Uint64(cursor[:8]))
+}
+
+// logConsumer serves a fixed event log, optionally delivering every batch twice
+type logConsumer struct {
+	events []es.Envelope
+	replay bool
+
+	mu      sync.Mutex
+	pending []es.Envelope // batch to deliver again on the next Fetch
+	drained chan struct{}
+	once    sync.Once
+}
+
+func (c *logConsumer) Fetch(ctx context.Context, cursor es.Cursor, limit int) ([]es.Envelope, es.Cursor, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.pending != nil {
+		batch := c.pending
+		c.pending = nil
+		return batch, cursor, nil
+	}
+
+	pos := min(position(cursor), len(c.events))
+	end := min(pos+limit, len(c.events))
+	if pos == end {
+		c.once.Do(func() { close(c.drained) })
+		return nil, cursor, nil
+	}
+
+	batch := c.events[pos:end]
+	if c.replay {
+		c.pending = batch
+	}
+	return batch, Position(end), nil
+}
+
+func (c *logConsumer) Commit(ctx context.Context, cursor es.Cursor) error {
+	return nil
+}
+
+func (c *logConsumer) Drained() <-chan struct{} {
+	return c.drained
+}
@@ -0,0 +1,107 @@
+package projectortest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/shogotsuneto/go-simple-es-projector"
+	es "github.com/shogotsuneto/go-simple-eventstore"
+)
+
+// tagStore is a toy projection: the set of tags and a per-tag counter
+type tagStore struct {
+	tags       map[string]bool
+	added      map[string]int
+	checkpoint es.Cursor
+}
+
+func newTagStore(t testing.TB) *tagStore {
+	return &tagStore{tags: map[string]bool{}, added: map[string]int{}}
+}
+
+func tagEvent(id, eventType, tag string) es.Envelope {
+	return es.Envelope{Event: es.Event{ID: id, Type: eventType, Data: []byte(tag)}}
+}
+
+// applyTags is idempotent: it only sets and deletes set members
+func applyTags(s *tagStore) projector.ApplyFunc {
+	return func(ctx context.Context, batch []es.Envelope, next es.Cursor) error {
+		for _, ev := range batch {
+			switch ev.Event.Type {
+			case "tag_added":
+				s.tags[string(ev.Event.Data)] = true
+			case "tag_removed":
+				delete(s.tags, string(ev.Event.Data))
+			}
+		}
+		s.checkpoint = next
+		return nil
+	}
+}
+
+// countTags is not idempotent: a redelivered batch is counted twice
+func countTags(s *tagStore) projector.ApplyFunc {
+	return func(ctx context.Context, batch []es.Envelope, next es.Cursor) error {
+		for _, ev := range batch {
+			s.added[string(ev.Event.Data)]++
+		}
+		s.checkpoint = next
+		return nil
+	}
+}
+
+var tagEvents = []es.Envelope{
+	tagEvent("1", "tag_added", "books"),
+	tagEvent("2", "tag_added", "fiction"),
+	tagEvent("3", "tag_removed", "books"),
+}
+
+func TestScenarioIdempotentProjection(t *testing.T) {
+	Scenario[*tagStore]{
+		Given: tagEvents,
+		New:   newTagStore,
+		Apply: applyTags,
+		Then: func(t testing.TB, s *tagStore) {
+			if len(s.tags) != 1 || !s.tags["fiction"] {
+				t.Errorf("expected only tag 'fiction', got %v", s.tags)
+			}
+		},
+		Checkpoint: func(s *tagStore) es.Cursor { return s.checkpoint },
+	}.Run(t)
+}
+
+func TestScenarioReplayDetectsNonIdempotentProjection(t *testing.T) {
+	scenario := Scenario[*tagStore]{
+		Given: tagEvents,
+		New:   newTagStore,
+		Apply: countTags,
+		Then: func(t testing.TB, s *tagStore) {
+			if s.added["books"] != 2 {
+				t.Errorf("expected 'books' counted twice, got %d", s.added["books"])
+			}
+		},
+	}
+
+	scenario.RunOnce(t)
+
+	ft := &fakeTB{}
+	scenario.RunReplayed(ft)
+	if !ft.failed {
+		t.Error("expected replayed run to catch the non-idempotent counter")
+	}
+}
+
+func TestScenarioCheckpointMismatch(t *testing.T) {
+	ft := &fakeTB{}
+	Scenario[*tagStore]{
+		Given:          tagEvents,
+		New:            newTagStore,
+		Apply:          applyTags,
+		Checkpoint:     func(s *tagStore) es.Cursor { return s.checkpoint },
+		WantCheckpoint: Position(2),
+	}.RunOnce(ft)
+
+	if !ft.failed {
+		t.Error("expected checkpoint mismatch to fail the scenario")
+	}
+}
@@ -0,0 +1,133 @@
+package projector
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// RateLimit throttles a Worker before each Apply with two token buckets, one for events
+// and one for batches, each refilling at its rate with a burst of one second's worth.
+// A batch larger than the burst is let through once the bucket has paid off the debt,
+// so the long-run rate holds regardless of BatchSize. Zero rates are unlimited.
+//
+// Set the initial rates in the struct literal; once the worker runs, change them with
+// SetEventsPerSecond and SetBatchesPerSecond, e.g. to throttle a backfill during peak hours.
+type RateLimit struct {
+	EventsPerSecond  float64 // 0: unlimited
+	BatchesPerSecond float64 // 0: unlimited
+
+	mu      sync.Mutex
+	events  bucket
+	batches bucket
+}
+
+// SetEventsPerSecond changes the event rate; 0 removes the limit.
+func (l *RateLimit) SetEventsPerSecond(rate float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.EventsPerSecond = rate
+}
+
+// SetBatchesPerSecond changes the batch rate; 0 removes the limit.
+func (l *RateLimit) SetBatchesPerSecond(rate float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.BatchesPerSecond = rate
+}
+
+// Limits returns the current event and batch rates.
+func (l *RateLimit) Limits() (eventsPerSecond, batchesPerSecond float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.EventsPerSecond, l.BatchesPerSecond
+}
+
+// reserve takes tokens for a batch of n events and returns how long to wait before applying it
+func (l *RateLimit) reserve(n int, now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return max(l.events.take(float64(n), l.EventsPerSecond, now), l.batches.take(1, l.BatchesPerSecond, now))
+}
+
+// bucket is a token bucket that may go into debt
+type bucket struct {
+	tokens  float64
+	last    time.Time
+	started bool
+}
+
+func (b *bucket) take(n, rate float64, now time.Time) time.Duration {
+	if rate <= 0 {
+		b.started = false // start full when a limit is set again
+		return 0
+	}
+
+	burst := math.Max(rate, 1)
+	if !b.started {
+		b.tokens, b.last, b.started = burst, now, true
+	}
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+
+	b.tokens -= n
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / rate * float64(time.Second))
+}
+
+// throttle waits until the RateLimit admits a batch of n events
+func (w *Worker) throttle(ctx context.Context, r *runState, n int) error {
+	d := w.RateLimit.reserve(n, r.clock.Now())
+	if d <= 0 {
+		return nil
+	}
+	w.logf("rate limited, waiting", "delay", d, "eventCount", n)
+
+	timer := r.clock.NewTimer(d)
+	select {
+	case <-ctx.Done():
+		timer.Stop()
+		w.logf("worker stopped due to context cancellation while rate limited")
+		return ctx.Err()
+	case <-timer.C():
+		return nil
+	}
+}
+
+// throughputMeter measures applied events per second over the last few batches
+type throughputMeter struct {
+	samples [10]throughputSample
+	n, next int
+}
+
+type throughputSample struct {
+	at     time.Time
+	events int
+}
+
+// observe records a committed batch and returns the current events/second
+func (m *throughputMeter) observe(at time.Time, events int) float64 {
+	m.samples[m.next] = throughputSample{at: at, events: events}
+	m.next = (m.next + 1) % len(m.samples)
+	if m.n < len(m.samples) {
+		m.n++
+	}
+	if m.n < 2 {
+		return 0
+	}
+
+	// The oldest sample only marks the start of the window
+	oldest := m.samples[(m.next-m.n+len(m.samples))%len(m.samples)]
+	total := 0
+	for i := 1; i < m.n; i++ {
+		total += m.samples[(m.next-m.n+i+len(m.samples))%len(m.samples)].events
+	}
+	elapsed := at.Sub(oldest.at).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(total) / elapsed
+}
@@ -0,0 +1,99 @@
+package projector
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	es "github.com/shogotsuneto/go-simple-eventstore"
+)
+
+func TestRateLimitReserve(t *testing.T) {
+	l := &RateLimit{EventsPerSecond: 100, BatchesPerSecond: 2}
+	now := time.Unix(0, 0)
+
+	// Full buckets admit one second's worth immediately
+	if d := l.reserve(100, now); d != 0 {
+		t.Errorf("expected burst to pass, got wait %v", d)
+	}
+	// Event bucket is empty: 50 more events take 500ms
+	if d := l.reserve(50, now); d != 500*time.Millisecond {
+		t.Errorf("expected 500ms wait, got %v", d)
+	}
+	// The batch bucket (burst 2) is in debt now as well
+	if d := l.reserve(0, now); d != 500*time.Millisecond {
+		t.Errorf("expected 500ms wait for the batch token, got %v", d)
+	}
+	// Refilled after a second
+	if d := l.reserve(0, now.Add(time.Second)); d != 0 {
+		t.Errorf("expected refilled buckets, got wait %v", d)
+	}
+
+	// Removing the limits lets everything through; setting them again starts full
+	l.SetEventsPerSecond(0)
+	l.SetBatchesPerSecond(0)
+	if d := l.reserve(1000000, now); d != 0 {
+		t.Errorf("expected unlimited, got wait %v", d)
+	}
+	l.SetEventsPerSecond(10)
+	if d := l.reserve(10, now); d != 0 {
+		t.Errorf("expected full bucket after re-enabling, got wait %v", d)
+	}
+	if events, batches := l.Limits(); events != 10 || batches != 0 {
+		t.Errorf("unexpected limits %v/%v", events, batches)
+	}
+}
+
+func TestThroughputMeter(t *testing.T) {
+	var m throughputMeter
+	start := time.Unix(0, 0)
+
+	if got := m.observe(start, 100); got != 0 {
+		t.Errorf("expected 0 with a single sample, got %v", got)
+	}
+	for i := 1; i <= 20; i++ {
+		got := m.observe(start.Add(time.Duration(i)*100*time.Millisecond), 10)
+		if got != 100 {
+			t.Fatalf("sample %d: expected 100 events/s, got %v", i, got)
+		}
+	}
+}
+
+func TestWorkerRateLimit(t *testing.T) {
+	big := make([]es.Envelope, 1000)
+	for i := range big {
+		big[i] = createTestEvent("big", "e")
+	}
+	consumer := newFakeConsumer()
+	consumer.AddBatch(big, es.Cursor("cursor1"))
+	consumer.AddBatch(big[:20], es.Cursor("cursor2"))
+
+	var waits []time.Duration
+	worker := &Worker{
+		Source:    consumer,
+		Start:     es.Cursor("start"),
+		IdleSleep: time.Millisecond,
+		RateLimit: &RateLimit{EventsPerSecond: 1000},
+		Apply:     func(ctx context.Context, batch []es.Envelope, next es.Cursor) error { return nil },
+		Logger: func(msg string, kv ...any) {
+			if msg == "rate limited, waiting" {
+				waits = append(waits, kv[1].(time.Duration))
+			}
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := worker.Run(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+
+	// The first batch uses the burst, the second waits for ~20 events' worth of tokens
+	if len(waits) != 1 || waits[0] <= 0 || waits[0] > 20*time.Millisecond {
+		t.Fatalf("expected one wait of at most 20ms, got %v", waits)
+	}
+	status := worker.Status()
+	if status.Events != 1020 || status.Throughput <= 0 || status.Throughput > 2000 {
+		t.Errorf("unexpected status: %d events at %.0f events/s", status.Events, status.Throughput)
+	}
+}
@@ -0,0 +1,261 @@
+package projector
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	es "github.com/shogotsuneto/go-simple-eventstore"
+)
+
+// ReorderBuffer restores per-stream version order for sources without global ordering
+// (e.g. parallel shards). It holds an event until the previous version of its stream has
+// been released, then releases both in order. Events without a StreamID or Version pass
+// straight through, as do versions at or below the last released one (redeliveries).
+// A stream the buffer has not seen yet continues the version Versions has for it, or
+// starts at version 1 if Versions does not know it. Without Versions there is nothing to
+// tell a new stream from one resumed mid-stream, so the lowest version in the batch the
+// stream first appears in becomes its baseline and earlier versions arriving later pass
+// through as redeliveries.
+//
+// The worker keeps fetching while events are held, but the cursor passed to Apply and
+// committed to the source only covers batches whose events have all been released, so a
+// restart never skips a held event.
+//
+// An event held longer than Timeout, or the oldest held events when more than MaxEvents
+// are held, give up on the missing versions: the stream's held events are handed to
+// OnTimeout (and not applied), or released in order if OnTimeout is nil.
+// Timeouts are checked on every poll.
+type ReorderBuffer struct {
+	MaxEvents int           // default: 10000 held events
+	Timeout   time.Duration // default: 30s
+	Versions  VersionStore  // optional, read-only; seeds streams (use the store Apply or the StreamValidator saves to)
+
+	// OnTimeout receives the held events of a stream that gave up waiting, in version
+	// order. Returning an error stops the worker.
+	OnTimeout func(ctx context.Context, t ReorderTimeout) error
+
+	mu       sync.Mutex
+	released map[string]int64 // last released version per stream
+	held     map[string][]heldEvent
+	count    int             // number of held events
+	batches  []*reorderBatch // fetched batches not yet fully released, in fetch order
+}
+
+// ReorderTimeout describes a stream whose missing versions never arrived.
+type ReorderTimeout struct {
+	StreamID string
+	Expected int64         // the version that was waited for
+	Held     []es.Envelope // held events, in version order
+}
+
+// heldEvent is an event waiting for its predecessor
+type heldEvent struct {
+	env   es.Envelope
+	batch *reorderBatch
+	at    time.Time
+}
+
+// reorderBatch tracks how many events of a fetched batch are still held
+type reorderBatch struct {
+	next es.Cursor
+	held int
+}
+
+// Held returns the number of events currently held.
+func (r *ReorderBuffer) Held() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.count
+}
+
+// push adds a fetched batch and returns the events now released, in order, and the cursor
+// up to which every fetched event has been released (nil if it did not move). A nil next
+// means nothing was fetched; push then only checks for timeouts.
+func (r *ReorderBuffer) push(ctx context.Context, batch []es.Envelope, next es.Cursor, now time.Time) ([]es.Envelope, es.Cursor, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.released == nil {
+		r.released = make(map[string]int64)
+		r.held = make(map[string][]heldEvent)
+	}
+
+	if err := r.seed(ctx, batch); err != nil {
+		return nil, nil, err
+	}
+
+	var out []es.Envelope
+	if next != nil {
+		b := &reorderBatch{next: next}
+		r.batches = append(r.batches, b)
+
+		for _, ev := range batch {
+			v := ev.Event.Version
+			switch {
+			case ev.StreamID == "" || v == 0:
+				out = append(out, ev)
+			case v <= r.released[ev.StreamID]+1:
+				out = append(out, ev)
+				if v > r.released[ev.StreamID] {
+					r.released[ev.StreamID] = v
+				}
+				out = r.drain(ev.StreamID, out)
+			default:
+				r.hold(ev, b, now)
+			}
+		}
+	}
+
+	out, err := r.expire(ctx, now, out)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return out, r.advance(), nil
+}
+
+// seed sets the last released version of streams seen for the first time: from Versions,
+// or else just below their lowest version in batch; r.mu must be held
+func (r *ReorderBuffer) seed(ctx context.Context, batch []es.Envelope) error {
+	var unknown []string
+	lowest := map[string]int64{}
+	for _, ev := range batch {
+		v := ev.Event.Version
+		if _, ok := r.released[ev.StreamID]; ok || ev.StreamID == "" || v == 0 {
+			continue
+		}
+		if low, seen := lowest[ev.StreamID]; !seen {
+			unknown = append(unknown, ev.StreamID)
+			lowest[ev.StreamID] = v
+		} else if v < low {
+			lowest[ev.StreamID] = v
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+
+	var versions map[string]int64
+	if r.Versions != nil {
+		var err error
+		if versions, err = r.Versions.LastVersions(ctx, unknown); err != nil {
+			return fmt.Errorf("failed to load stream versions: %w", err)
+		}
+	}
+	for _, id := range unknown {
+		switch v, ok := versions[id]; {
+		case ok:
+			r.released[id] = v
+		case r.Versions != nil:
+			r.released[id] = 0 // a new stream: wait for version 1
+		default:
+			r.released[id] = lowest[id] - 1 // possibly resumed mid-stream: nothing to wait for
+		}
+	}
+	return nil
+}
+
+// hold buffers ev in version order; r.mu must be held
+func (r *ReorderBuffer) hold(ev es.Envelope, b *reorderBatch, now time.Time) {
+	held := append(r.held[ev.StreamID], heldEvent{env: ev, batch: b, at: now})
+	sort.SliceStable(held, func(i, j int) bool { return held[i].env.Event.Version < held[j].env.Event.Version })
+	r.held[ev.StreamID] = held
+	b.held++
+	r.count++
+}
+
+// drain releases held events of stream that now continue it; r.mu must be held
+func (r *ReorderBuffer) drain(stream string, out []es.Envelope) []es.Envelope {
+	held := r.held[stream]
+	for len(held) > 0 && held[0].env.Event.Version <= r.released[stream]+1 {
+		h := held[0]
+		held = held[1:]
+		r.unhold(h)
+		out = append(out, h.env)
+		if v := h.env.Event.Version; v > r.released[stream] {
+			r.released[stream] = v
+		}
+	}
+	r.setHeld(stream, held)
+	return out
+}
+
+// expire gives up on streams whose oldest held event timed out or that must make room; r.mu must be held
+func (r *ReorderBuffer) expire(ctx context.Context, now time.Time, out []es.Envelope) ([]es.Envelope, error) {
+	timeout := r.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	maxEvents := r.MaxEvents
+	if maxEvents <= 0 {
+		maxEvents = 10000
+	}
+
+	for r.count > 0 {
+		stream, oldest := r.oldest()
+		if now.Sub(oldest) < timeout && r.count <= maxEvents {
+			return out, nil
+		}
+
+		held := r.held[stream]
+		events := make([]es.Envelope, len(held))
+		for i, h := range held {
+			events[i] = h.env
+			r.unhold(h)
+		}
+		r.setHeld(stream, nil)
+		expected := r.released[stream] + 1
+		r.released[stream] = events[len(events)-1].Event.Version
+
+		if r.OnTimeout != nil {
+			if err := r.OnTimeout(ctx, ReorderTimeout{StreamID: stream, Expected: expected, Held: events}); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		out = append(out, events...)
+	}
+	return out, nil
+}
+
+// oldest returns the stream holding the longest-waiting event; r.mu must be held
+func (r *ReorderBuffer) oldest() (string, time.Time) {
+	var stream string
+	var at time.Time
+	for id, held := range r.held {
+		for _, h := range held {
+			if stream == "" || h.at.Before(at) || h.at.Equal(at) && id < stream {
+				stream, at = id, h.at
+			}
+		}
+	}
+	return stream, at
+}
+
+// advance drops fully released batches and returns the cursor after the last of them; r.mu must be held
+func (r *ReorderBuffer) advance() es.Cursor {
+	var cursor es.Cursor
+	for len(r.batches) > 0 && r.batches[0].held == 0 {
+		cursor = r.batches[0].next
+		r.batches = r.batches[1:]
+	}
+	return cursor
+}
+
+// unhold accounts for an event leaving the buffer; r.mu must be held
+func (r *ReorderBuffer) unhold(h heldEvent) {
+	h.batch.held--
+	r.count--
+}
+
+// setHeld stores a stream's held events, dropping empty entries; r.mu must be held
+func (r *ReorderBuffer) setHeld(stream string, held []heldEvent) {
+	if len(held) == 0 {
+		delete(r.held, stream)
+		return
+	}
+	r.held[stream] = held
+}
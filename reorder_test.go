@@ -0,0 +1,179 @@
+package projector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	es "github.com/shogotsuneto/go-simple-eventstore"
+)
+
+func TestWorkerReorderBufferRestoresStreamOrder(t *testing.T) {
+	consumer := newFakeConsumer()
+	consumer.AddBatch([]es.Envelope{streamEvent("a", 1, "a1"), streamEvent("a", 3, "a3"), streamEvent("b", 1, "b1")}, es.Cursor("cursor1"))
+	consumer.AddBatch([]es.Envelope{streamEvent("a", 4, "a4"), streamEvent("b", 2, "b2")}, es.Cursor("cursor2"))
+	consumer.AddBatch([]es.Envelope{streamEvent("a", 2, "a2")}, es.Cursor("cursor3"))
+
+	applied := []appliedBatch{}
+	worker := &Worker{
+		Source:    consumer,
+		Start:     es.Cursor("start"),
+		Reorder:   &ReorderBuffer{},
+		IdleSleep: time.Millisecond,
+		Apply: func(ctx context.Context, batch []es.Envelope, next es.Cursor) error {
+			applied = append(applied, appliedBatch{batch: batch, cursor: next})
+			return nil
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := worker.Run(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+
+	want := []struct {
+		ids    string
+		cursor string
+	}{
+		{"[a1 b1]", "start"},      // a3 held, so cursor1 cannot be committed yet
+		{"[b2]", "start"},         // a4 held as well
+		{"[a2 a3 a4]", "cursor3"}, // a2 releases the rest of the stream
+	}
+	if len(applied) != len(want) {
+		t.Fatalf("expected %d apply calls, got %d", len(want), len(applied))
+	}
+	for i, w := range want {
+		if got := fmt.Sprint(eventIDsOf(applied[i].batch)); got != w.ids {
+			t.Errorf("apply %d: expected events %s, got %s", i, w.ids, got)
+		}
+		if string(applied[i].cursor) != w.cursor {
+			t.Errorf("apply %d: expected cursor %q, got %q", i, w.cursor, applied[i].cursor)
+		}
+	}
+
+	// Fetching continued past held events
+	if got := string(consumer.fetchCalls[2].cursor); got != "cursor2" {
+		t.Errorf("expected third fetch from cursor2, got %q", got)
+	}
+	if got := string(consumer.commitCalls[len(consumer.commitCalls)-1]); got != "cursor3" {
+		t.Errorf("expected last commit cursor3, got %q", got)
+	}
+}
+
+func TestReorderBufferTimeoutReleasesHeldEvents(t *testing.T) {
+	r := &ReorderBuffer{Timeout: time.Minute}
+	ctx := context.Background()
+	now := time.Unix(0, 0)
+
+	out, commit, _ := r.push(ctx, []es.Envelope{streamEvent("a", 1, "a1"), streamEvent("a", 3, "a3")}, es.Cursor("c1"), now)
+	if got := fmt.Sprint(eventIDsOf(out)); got != "[a1]" || commit != nil {
+		t.Fatalf("expected a1 released and no commit, got %s / %q", got, commit)
+	}
+
+	out, commit, _ = r.push(ctx, nil, nil, now.Add(2*time.Minute))
+	if got := fmt.Sprint(eventIDsOf(out)); got != "[a3]" || string(commit) != "c1" {
+		t.Fatalf("expected a3 released after timeout with commit c1, got %s / %q", got, commit)
+	}
+
+	// The gap was given up: a4 continues the stream
+	out, _, _ = r.push(ctx, []es.Envelope{streamEvent("a", 4, "a4")}, es.Cursor("c2"), now.Add(3*time.Minute))
+	if got := fmt.Sprint(eventIDsOf(out)); got != "[a4]" {
+		t.Errorf("expected a4 released, got %s", got)
+	}
+}
+
+func TestReorderBufferOnTimeoutHandler(t *testing.T) {
+	var timeouts []ReorderTimeout
+	r := &ReorderBuffer{
+		MaxEvents: 1,
+		OnTimeout: func(ctx context.Context, rt ReorderTimeout) error {
+			timeouts = append(timeouts, rt)
+			return nil
+		},
+	}
+	ctx := context.Background()
+	now := time.Unix(0, 0)
+
+	// Two held events exceed MaxEvents: the stream is handed to OnTimeout
+	out, commit, err := r.push(ctx, []es.Envelope{streamEvent("a", 1, "a1"), streamEvent("a", 3, "a3"), streamEvent("a", 4, "a4")}, es.Cursor("c1"), now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := fmt.Sprint(eventIDsOf(out)); got != "[a1]" || string(commit) != "c1" {
+		t.Errorf("expected only a1 released and commit c1, got %s / %q", got, commit)
+	}
+	if len(timeouts) != 1 || timeouts[0].StreamID != "a" || timeouts[0].Expected != 2 || len(timeouts[0].Held) != 2 {
+		t.Fatalf("unexpected timeouts: %+v", timeouts)
+	}
+	if r.Held() != 0 {
+		t.Errorf("expected empty buffer, got %d held", r.Held())
+	}
+
+	handlerErr := errors.New("park failed")
+	r.OnTimeout = func(ctx context.Context, rt ReorderTimeout) error { return handlerErr }
+	if _, _, err := r.push(ctx, []es.Envelope{streamEvent("b", 1, "b1"), streamEvent("b", 5, "b5"), streamEvent("b", 6, "b6")}, es.Cursor("c2"), now); !errors.Is(err, handlerErr) {
+		t.Errorf("expected handler error, got %v", err)
+	}
+}
+
+func TestReorderBufferSeedsFromVersionStore(t *testing.T) {
+	store := &MemoryVersionStore{}
+	_ = store.SaveVersions(context.Background(), map[string]int64{"a": 7})
+
+	r := &ReorderBuffer{Versions: store}
+	out, commit, _ := r.push(context.Background(), []es.Envelope{streamEvent("a", 8, "a8")}, es.Cursor("c1"), time.Unix(0, 0))
+	if got := fmt.Sprint(eventIDsOf(out)); got != "[a8]" || string(commit) != "c1" {
+		t.Errorf("expected a8 to continue stored version 7, got %s / %q", got, commit)
+	}
+}
+
+func TestReorderBufferWaitsForFirstVersionOfNewStream(t *testing.T) {
+	r := &ReorderBuffer{Versions: &MemoryVersionStore{}}
+	ctx := context.Background()
+	now := time.Unix(0, 0)
+
+	// The store does not know stream a, so v2 waits for v1 in a later batch
+	out, commit, _ := r.push(ctx, []es.Envelope{streamEvent("a", 2, "a2")}, es.Cursor("c1"), now)
+	if len(out) != 0 || commit != nil {
+		t.Fatalf("expected a2 held, got %v / %q", eventIDsOf(out), commit)
+	}
+	out, commit, _ = r.push(ctx, []es.Envelope{streamEvent("a", 1, "a1")}, es.Cursor("c2"), now)
+	if got := fmt.Sprint(eventIDsOf(out)); got != "[a1 a2]" || string(commit) != "c2" {
+		t.Errorf("expected a1 then a2 with commit c2, got %s / %q", got, commit)
+	}
+}
+
+func TestWorkerReorderBufferResumesMidStream(t *testing.T) {
+	consumer := newFakeConsumer()
+	// Versions 1 and 2 were applied before the restart
+	consumer.AddBatch([]es.Envelope{streamEvent("p-1", 3, "p3")}, es.Cursor("cursor3"))
+
+	var timeouts []ReorderTimeout
+	var applied []string
+	worker := &Worker{
+		Source:    consumer,
+		Start:     es.Cursor("cursor2"),
+		StopAtEnd: true,
+		Reorder: &ReorderBuffer{OnTimeout: func(ctx context.Context, rt ReorderTimeout) error {
+			timeouts = append(timeouts, rt)
+			return nil
+		}},
+		Apply: func(ctx context.Context, batch []es.Envelope, next es.Cursor) error {
+			applied = append(applied, eventIDsOf(batch)...)
+			return nil
+		},
+	}
+
+	if err := worker.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fmt.Sprint(applied) != "[p3]" || len(timeouts) != 0 {
+		t.Errorf("expected p3 applied without waiting, got %v (timeouts %v)", applied, timeouts)
+	}
+	if st := worker.Status(); st.Events != 1 || string(st.Cursor) != "cursor3" {
+		t.Errorf("expected 1 event committed at cursor3, got %+v", st)
+	}
+}
@@ -0,0 +1,33 @@
+package projector
+
+import (
+	"time"
+
+	es "github.com/shogotsuneto/go-simple-eventstore"
+)
+
+// Status is a point-in-time snapshot of a Worker, safe to read while it runs.
+type Status struct {
+	Running     bool      // true while Run is executing
+	Cursor      es.Cursor // last committed cursor (Start until the first batch is committed)
+	BatchSize   int       // fetch limit used for the most recent Fetch
+	Batches     int64     // batches applied since Run started
+	Events      int64     // events applied since Run started
+	Filtered    int64     // events dropped by Worker.Filter since Run started
+	Throughput  float64   // applied events per second over the last 10 batches, including rate limit waits
+	LastBatchAt time.Time // when the most recent batch was committed
+}
+
+// Status returns a snapshot of the worker's progress.
+func (w *Worker) Status() Status {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.status
+}
+
+// updateStatus mutates the status snapshot under the worker's lock
+func (w *Worker) updateStatus(fn func(s *Status)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	fn(&w.status)
+}
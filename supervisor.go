@@ -0,0 +1,220 @@
+package projector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	es "github.com/shogotsuneto/go-simple-eventstore"
+)
+
+// CheckpointStore loads the checkpoint of a named projection. Saving stays the job of
+// Apply, which persists the cursor together with the read model.
+type CheckpointStore interface {
+	Load(ctx context.Context, name string) (es.Cursor, error)
+}
+
+// CheckpointFunc adapts an ordinary function to CheckpointStore.
+type CheckpointFunc func(ctx context.Context, name string) (es.Cursor, error)
+
+// Load implements CheckpointStore.
+func (f CheckpointFunc) Load(ctx context.Context, name string) (es.Cursor, error) {
+	return f(ctx, name)
+}
+
+// Checkpoint is a stored projection checkpoint.
+type Checkpoint struct {
+	Name      string
+	Cursor    es.Cursor
+	UpdatedAt time.Time
+}
+
+// CheckpointAdmin is a CheckpointStore that operational tooling (cmd/projectorctl) can
+// list and overwrite. Put bypasses the projection's transaction, so stop the worker
+// before moving its checkpoint.
+type CheckpointAdmin interface {
+	CheckpointStore
+	List(ctx context.Context) ([]Checkpoint, error) // sorted by name
+	Put(ctx context.Context, name string, cursor es.Cursor) error
+}
+
+// CrashLoopError is returned by Supervisor.Run when a worker failed MaxFailures times
+// within Window. It wraps the worker's last error.
+type CrashLoopError struct {
+	Name     string
+	Failures int
+	Window   time.Duration
+	Err      error // last failure
+}
+
+func (e *CrashLoopError) Error() string {
+	return fmt.Sprintf("projector: worker %q crash loop (%d failures within %s): %v", e.Name, e.Failures, e.Window, e.Err)
+}
+
+func (e *CrashLoopError) Unwrap() error { return e.Err }
+
+// Supervisor runs a set of named workers and restarts them when Run returns an error.
+// Before each (re)start a worker's Start is reloaded from Checkpoints, or set to the
+// last cursor it committed when Checkpoints is nil. A worker failing MaxFailures times
+// within Window is a crash loop: the supervisor stops every worker and returns a
+// *CrashLoopError. An Apply failure that leaves a worker's Breaker open does not count,
+// since the restarted Run waits out the cool-down itself; keep FailureThreshold below
+// MaxFailures.
+type Supervisor struct {
+	Workers     map[string]*Worker          // workers by projection name
+	Checkpoints CheckpointStore             // optional; loads each worker's Start by name
+	Backoff     PollStrategy                // delay before the n-th restart within Window; default: ExponentialPoll{Min: 1s, Max: 1m}
+	MaxFailures int                         // default: 5
+	Window      time.Duration               // default: 10m
+	Logger      func(msg string, kv ...any) // optional, nil-safe
+	Clock       Clock                       // optional; defaults to the real clock
+}
+
+// Run starts every worker and blocks until ctx is done, every worker returned nil, or a
+// worker is crash-looping. Workers share ctx, so cancelling it shuts all of them down.
+func (s *Supervisor) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errs := make(chan error, len(s.Workers))
+	var wg sync.WaitGroup
+	for name, w := range s.Workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := s.supervise(ctx, name, w)
+			if err != nil && ctx.Err() == nil {
+				cancel() // escalate: stop the other workers
+			}
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	var crash error
+	for err := range errs {
+		var loop *CrashLoopError
+		if errors.As(err, &loop) && crash == nil {
+			crash = err
+		}
+	}
+	if crash != nil {
+		return crash
+	}
+	return ctx.Err()
+}
+
+// supervise runs one worker until it returns nil, ctx is done or it is crash-looping
+func (s *Supervisor) supervise(ctx context.Context, name string, w *Worker) error {
+	backoff := s.Backoff
+	if backoff == nil {
+		backoff = ExponentialPoll{Min: time.Second, Max: time.Minute}
+	}
+	maxFailures := s.MaxFailures
+	if maxFailures <= 0 {
+		maxFailures = 5
+	}
+	window := s.Window
+	if window <= 0 {
+		window = 10 * time.Minute
+	}
+	clock := s.clock()
+
+	var failures []time.Time
+	for {
+		err := s.start(ctx, name, w)
+		if err == nil {
+			s.logf("worker finished", "worker", name)
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		// The Apply failure that opened a worker's circuit is not a crash loop: the
+		// restarted worker waits out the cool-down. Anything else counts, including
+		// failures while the circuit is half-open that never reached Apply.
+		if tripped(w, err) {
+			delay := backoff.IdleDelay(max(len(failures), 1))
+			s.logf("worker failed with circuit open, restarting", "worker", name, "error", err, "backoff", delay)
+			if err := s.sleep(ctx, clock, delay); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// Only failures within the window count towards a crash loop
+		now := clock.Now()
+		recent := failures[:0]
+		for _, t := range failures {
+			if now.Sub(t) < window {
+				recent = append(recent, t)
+			}
+		}
+		failures = append(recent, now)
+
+		if len(failures) >= maxFailures {
+			s.logf("worker crash loop, stopping", "worker", name, "failures", len(failures), "window", window, "error", err)
+			return &CrashLoopError{Name: name, Failures: len(failures), Window: window, Err: err}
+		}
+
+		delay := backoff.IdleDelay(len(failures))
+		s.logf("worker failed, restarting", "worker", name, "error", err, "failures", len(failures), "backoff", delay)
+		if err := s.sleep(ctx, clock, delay); err != nil {
+			return err
+		}
+	}
+}
+
+// tripped reports whether err is the Apply failure that left w's circuit open
+func tripped(w *Worker, err error) bool {
+	var perr *Error
+	return w.Breaker != nil && w.Breaker.State() == BreakerOpen &&
+		errors.As(err, &perr) && perr.Phase == PhaseApply
+}
+
+// sleep waits out a restart delay
+func (s *Supervisor) sleep(ctx context.Context, clock Clock, delay time.Duration) error {
+	timer := clock.NewTimer(delay)
+	select {
+	case <-ctx.Done():
+		timer.Stop()
+		return ctx.Err()
+	case <-timer.C():
+	}
+	return nil
+}
+
+// start positions the worker at its checkpoint and runs it
+func (s *Supervisor) start(ctx context.Context, name string, w *Worker) error {
+	if s.Checkpoints != nil {
+		cursor, err := s.Checkpoints.Load(ctx, name)
+		if err != nil {
+			return fmt.Errorf("load checkpoint: %w", err)
+		}
+		w.Start = cursor
+	} else if st := w.Status(); st.Cursor != nil {
+		w.Start = st.Cursor // resume after the last committed batch
+	}
+
+	s.logf("starting worker", "worker", name, "cursor", w.Start)
+	return w.Run(ctx)
+}
+
+// clock returns the configured clock or the real one
+func (s *Supervisor) clock() Clock {
+	if s.Clock != nil {
+		return s.Clock
+	}
+	return realClock{}
+}
+
+// logf is a nil-safe logging helper
+func (s *Supervisor) logf(msg string, kv ...any) {
+	if s.Logger != nil {
+		s.Logger(msg, kv...)
+	}
+}
@@ -0,0 +1,219 @@
+package projector
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	es "github.com/shogotsuneto/go-simple-eventstore"
+)
+
+func TestSupervisorRestartsFromCheckpoint(t *testing.T) {
+	consumer := newFakeConsumer()
+	consumer.AddBatch([]es.Envelope{createTestEvent("1", "e")}, es.Cursor("cursor1"))
+	consumer.AddBatch([]es.Envelope{createTestEvent("2", "e")}, es.Cursor("cursor2"))
+	consumer.AddBatch([]es.Envelope{createTestEvent("2", "e")}, es.Cursor("cursor2")) // redelivered after the restart
+
+	var mu sync.Mutex
+	saved := map[string]es.Cursor{}
+	failOnce := true
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	worker := &Worker{
+		Source:    consumer,
+		IdleSleep: time.Millisecond,
+		Apply: func(ctx context.Context, batch []es.Envelope, next es.Cursor) error {
+			mu.Lock()
+			defer mu.Unlock()
+			if string(next) == "cursor2" && failOnce {
+				failOnce = false
+				return errors.New("projection db unavailable")
+			}
+			saved["products"] = next
+			if string(next) == "cursor2" {
+				cancel() // done
+			}
+			return nil
+		},
+	}
+
+	var logs []string
+	s := &Supervisor{
+		Workers: map[string]*Worker{"products": worker},
+		Checkpoints: CheckpointFunc(func(ctx context.Context, name string) (es.Cursor, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			return saved[name], nil
+		}),
+		Backoff: FixedPoll(time.Millisecond),
+		Logger:  func(msg string, kv ...any) { logs = append(logs, msg) },
+	}
+
+	if err := s.Run(ctx); err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+
+	// Second run starts from the checkpoint saved by the first
+	var starts []string
+	for _, call := range consumer.fetchCalls {
+		starts = append(starts, string(call.cursor))
+	}
+	if len(starts) < 3 || starts[0] != "" || starts[2] != "cursor1" {
+		t.Errorf("expected restart from cursor1, got fetch cursors %q", starts)
+	}
+	if got := string(saved["products"]); got != "cursor2" {
+		t.Errorf("expected checkpoint cursor2, got %q", got)
+	}
+	if !containsString(logs, "worker failed, restarting") {
+		t.Errorf("expected restart to be logged, got %q", logs)
+	}
+}
+
+func TestSupervisorStopsOnCrashLoop(t *testing.T) {
+	applyErr := errors.New("apply failed")
+	failing := newFakeConsumer()
+	for i := 0; i < 3; i++ {
+		failing.AddBatch([]es.Envelope{createTestEvent("1", "e")}, es.Cursor("cursor1"))
+	}
+
+	healthyStopped := make(chan struct{})
+	healthy := &Worker{
+		Source:    newFakeConsumer(),
+		IdleSleep: time.Millisecond,
+		Apply:     func(ctx context.Context, batch []es.Envelope, next es.Cursor) error { return nil },
+	}
+
+	s := &Supervisor{
+		Workers: map[string]*Worker{
+			"failing": {
+				Source: failing,
+				Apply:  func(ctx context.Context, batch []es.Envelope, next es.Cursor) error { return applyErr },
+			},
+			"healthy": healthy,
+		},
+		Backoff:     FixedPoll(time.Millisecond),
+		MaxFailures: 3,
+		Window:      time.Minute,
+	}
+
+	go func() {
+		defer close(healthyStopped)
+		err := s.Run(context.Background())
+
+		var loop *CrashLoopError
+		if !errors.As(err, &loop) {
+			t.Errorf("expected *CrashLoopError, got %v", err)
+			return
+		}
+		if loop.Name != "failing" || loop.Failures != 3 || !errors.Is(err, applyErr) {
+			t.Errorf("unexpected crash loop error: %v", err)
+		}
+	}()
+
+	select {
+	case <-healthyStopped:
+	case <-time.After(time.Second):
+		t.Fatal("supervisor did not stop on crash loop")
+	}
+	if healthy.Status().Running {
+		t.Error("expected healthy worker to be stopped too")
+	}
+}
+
+func TestSupervisorWaitsOnOpenBreaker(t *testing.T) {
+	consumer := newFakeConsumer()
+	for i := 0; i < 7; i++ {
+		consumer.AddBatch([]es.Envelope{createTestEvent("1", "e")}, es.Cursor("cursor1"))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The target is down for 6 attempts: more than MaxFailures, but all but the first
+	// happen with the circuit open or half-open
+	attempts := 0
+	worker := &Worker{
+		Source:    consumer,
+		IdleSleep: time.Millisecond,
+		Breaker:   &CircuitBreaker{FailureThreshold: 2, CoolDown: time.Millisecond},
+		Hooks: Hooks{OnBreakerStateChange: func(t BreakerTransition) {
+			if t.To == BreakerClosed {
+				cancel() // recovered
+			}
+		}},
+		Apply: func(ctx context.Context, batch []es.Envelope, next es.Cursor) error {
+			attempts++
+			if attempts <= 6 {
+				return errors.New("projection db unavailable")
+			}
+			return nil
+		},
+	}
+
+	s := &Supervisor{
+		Workers:     map[string]*Worker{"products": worker},
+		Backoff:     FixedPoll(time.Millisecond),
+		MaxFailures: 3,
+		Window:      time.Minute,
+	}
+
+	if err := s.Run(ctx); err != context.Canceled {
+		t.Fatalf("expected the worker to recover instead of crash-looping, got %v", err)
+	}
+	if attempts != 7 {
+		t.Errorf("expected 7 apply attempts, got %d", attempts)
+	}
+	if len(consumer.commitCalls) != 1 {
+		t.Errorf("expected only the successful batch to be committed, got %d commits", len(consumer.commitCalls))
+	}
+}
+
+func TestSupervisorCountsFetchFailuresWhileHalfOpen(t *testing.T) {
+	consumer := newFakeConsumer()
+	consumer.AddBatch([]es.Envelope{createTestEvent("1", "e")}, es.Cursor("cursor1"))
+
+	fetchErr := errors.New("eventstore unavailable")
+	worker := &Worker{
+		Source:  consumer,
+		Breaker: &CircuitBreaker{FailureThreshold: 1, CoolDown: time.Millisecond},
+		Apply: func(ctx context.Context, batch []es.Envelope, next es.Cursor) error {
+			consumer.SetFetchError(fetchErr) // the trial batches never reach Apply
+			return errors.New("projection db unavailable")
+		},
+	}
+
+	s := &Supervisor{
+		Workers:     map[string]*Worker{"products": worker},
+		Backoff:     FixedPoll(time.Millisecond),
+		MaxFailures: 3,
+		Window:      time.Minute,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	err := s.Run(ctx)
+
+	var loop *CrashLoopError
+	if !errors.As(err, &loop) || loop.Failures != 3 || !errors.Is(err, fetchErr) {
+		t.Fatalf("expected a crash loop of fetch failures, got %v", err)
+	}
+	if st := worker.Breaker.State(); st != BreakerHalfOpen {
+		t.Errorf("expected the circuit to stay half-open, got %s", st)
+	}
+	if n := len(consumer.fetchCalls); n != 4 {
+		t.Errorf("expected 1 fetch and 3 failed trials, got %d fetches", n)
+	}
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
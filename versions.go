@@ -0,0 +1,228 @@
+package projector
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	es "github.com/shogotsuneto/go-simple-eventstore"
+)
+
+// VersionStore persists the last applied version of each stream for a StreamValidator.
+// Implement it on top of your checkpoint storage to keep versions and cursor consistent.
+type VersionStore interface {
+	// LastVersions returns the last applied version of each of streamIDs; unknown streams are omitted.
+	LastVersions(ctx context.Context, streamIDs []string) (map[string]int64, error)
+	// SaveVersions records versions after a batch has been applied.
+	SaveVersions(ctx context.Context, versions map[string]int64) error
+}
+
+// Compile-time interface compliance check
+var _ VersionStore = (*MemoryVersionStore)(nil)
+
+// MemoryVersionStore is an in-memory VersionStore. It is the default of StreamValidator.
+type MemoryVersionStore struct {
+	mu       sync.Mutex
+	versions map[string]int64
+}
+
+// LastVersions implements VersionStore.
+func (m *MemoryVersionStore) LastVersions(ctx context.Context, streamIDs []string) (map[string]int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	out := make(map[string]int64, len(streamIDs))
+	for _, id := range streamIDs {
+		if v, ok := m.versions[id]; ok {
+			out[id] = v
+		}
+	}
+	return out, nil
+}
+
+// SaveVersions implements VersionStore.
+func (m *MemoryVersionStore) SaveVersions(ctx context.Context, versions map[string]int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.versions == nil {
+		m.versions = make(map[string]int64, len(versions))
+	}
+	for id, v := range versions {
+		m.versions[id] = v
+	}
+	return nil
+}
+
+type pendingVersionsKey struct{}
+
+// PendingVersions returns the stream versions the Worker's StreamValidator will save once
+// the current Apply call succeeds. Save them with the checkpoint in Apply's transaction
+// so versions and cursor never diverge.
+func PendingVersions(ctx context.Context) (map[string]int64, bool) {
+	v, ok := ctx.Value(pendingVersionsKey{}).(map[string]int64)
+	return v, ok
+}
+
+func withPendingVersions(ctx context.Context, versions map[string]int64) context.Context {
+	return context.WithValue(ctx, pendingVersionsKey{}, versions)
+}
+
+// AnomalyKind classifies a stream version anomaly.
+type AnomalyKind int
+
+const (
+	// AnomalyGap means versions were skipped (got > expected).
+	AnomalyGap AnomalyKind = iota + 1
+	// AnomalyDuplicate means a version was seen again (got == last applied).
+	AnomalyDuplicate
+	// AnomalyRegression means a version went backwards (got < last applied).
+	AnomalyRegression
+)
+
+func (k AnomalyKind) String() string {
+	switch k {
+	case AnomalyGap:
+		return "gap"
+	case AnomalyDuplicate:
+		return "duplicate"
+	case AnomalyRegression:
+		return "regression"
+	default:
+		return fmt.Sprintf("AnomalyKind(%d)", int(k))
+	}
+}
+
+// StreamAnomaly describes an event whose version does not follow its stream's last applied version.
+type StreamAnomaly struct {
+	Kind     AnomalyKind
+	StreamID string
+	EventID  string
+	Expected int64 // last applied version + 1
+	Got      int64
+}
+
+func (a StreamAnomaly) Error() string {
+	return fmt.Sprintf("stream %q: version %s: expected %d, got %d (event %q)", a.StreamID, a.Kind, a.Expected, a.Got, a.EventID)
+}
+
+// AnomalyPolicy decides what a StreamValidator does about an anomaly.
+type AnomalyPolicy int
+
+const (
+	// PolicyLog reports anomalies and applies every event anyway.
+	PolicyLog AnomalyPolicy = iota
+	// PolicySkip reports anomalies and drops duplicate and regressed events before Apply.
+	// Gapped events are still applied: the missing versions cannot be recovered by waiting.
+	PolicySkip
+	// PolicyHalt reports the first anomaly and makes Run return it (as a StreamAnomaly error)
+	// before the batch is applied.
+	PolicyHalt
+)
+
+// StreamValidator tracks the last applied version per stream and checks that every
+// event continues its stream contiguously (version == last + 1). For a stream without a
+// recorded version, e.g. after resuming from a checkpoint with an empty store, the first
+// version seen is the baseline. Events without a StreamID or with a zero Version are not
+// checked.
+//
+// Anomalies are logged and reported to Hooks.OnStreamAnomaly, then handled per Policy.
+// Versions are saved to Store after Apply succeeds, outside Apply's transaction; to keep
+// them consistent with the checkpoint across crashes, save PendingVersions(ctx) from
+// Apply in the same transaction (SaveVersions then repeats the write and must be idempotent).
+type StreamValidator struct {
+	Store  VersionStore  // default: a MemoryVersionStore
+	Policy AnomalyPolicy // default: PolicyLog
+
+	once sync.Once
+}
+
+// store returns the configured store, creating the default one on first use
+func (v *StreamValidator) store() VersionStore {
+	v.once.Do(func() {
+		if v.Store == nil {
+			v.Store = &MemoryVersionStore{}
+		}
+	})
+	return v.Store
+}
+
+// check validates batch against the stored versions. It returns the events to apply
+// and the versions to save once they are applied.
+func (v *StreamValidator) check(ctx context.Context, batch []es.Envelope, report func(StreamAnomaly)) ([]es.Envelope, map[string]int64, error) {
+	var streams []string
+	seen := map[string]bool{}
+	for _, ev := range batch {
+		if ev.StreamID != "" && !seen[ev.StreamID] {
+			seen[ev.StreamID] = true
+			streams = append(streams, ev.StreamID)
+		}
+	}
+	if len(streams) == 0 {
+		return batch, nil, nil
+	}
+
+	stored, err := v.store().LastVersions(ctx, streams)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load stream versions: %w", err)
+	}
+	// Copy, as the store's map may be nil or shared
+	last := make(map[string]int64, len(stored))
+	for id, version := range stored {
+		last[id] = version
+	}
+
+	updates := make(map[string]int64)
+	kept := make([]es.Envelope, 0, len(batch))
+	for _, ev := range batch {
+		got := ev.Event.Version
+		if ev.StreamID == "" || got == 0 {
+			kept = append(kept, ev)
+			continue
+		}
+
+		prev, known := last[ev.StreamID]
+		if !known {
+			// Resuming mid-stream: the first version seen is the baseline
+			prev = got - 1
+		}
+		var kind AnomalyKind
+		switch {
+		case got > prev+1:
+			kind = AnomalyGap
+		case got == prev:
+			kind = AnomalyDuplicate
+		case got < prev:
+			kind = AnomalyRegression
+		}
+
+		if kind != 0 {
+			a := StreamAnomaly{Kind: kind, StreamID: ev.StreamID, EventID: ev.Event.ID, Expected: prev + 1, Got: got}
+			report(a)
+			if v.Policy == PolicyHalt {
+				return nil, nil, a
+			}
+			if v.Policy == PolicySkip && kind != AnomalyGap {
+				continue
+			}
+		}
+
+		kept = append(kept, ev)
+		if got > prev {
+			last[ev.StreamID] = got
+			updates[ev.StreamID] = got
+		}
+	}
+	return kept, updates, nil
+}
+
+// save records applied versions
+func (v *StreamValidator) save(ctx context.Context, updates map[string]int64) error {
+	if len(updates) == 0 {
+		return nil
+	}
+	if err := v.store().SaveVersions(ctx, updates); err != nil {
+		return fmt.Errorf("failed to save stream versions: %w", err)
+	}
+	return nil
+}
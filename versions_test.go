@@ -0,0 +1,193 @@
+package projector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	es "github.com/shogotsuneto/go-simple-eventstore"
+)
+
+func streamEvent(streamID string, version int64, id string) es.Envelope {
+	return es.Envelope{StreamID: streamID, Event: es.Event{ID: id, Type: "test.event", Version: version}}
+}
+
+// producerBugBatches has a gap on stream a, a reused version on stream b and a regression on a
+func producerBugBatches(consumer *fakeConsumer) {
+	consumer.AddBatch([]es.Envelope{
+		streamEvent("a", 1, "a1"),
+		streamEvent("b", 1, "b1"),
+		streamEvent("a", 3, "a3"), // gap: a2 missing
+	}, es.Cursor("cursor1"))
+	consumer.AddBatch([]es.Envelope{
+		streamEvent("b", 1, "b1-reused"), // duplicate version
+		streamEvent("a", 2, "a2-late"),   // regression
+		streamEvent("b", 2, "b2"),
+	}, es.Cursor("cursor2"))
+}
+
+func runValidator(t *testing.T, validator *StreamValidator) ([]StreamAnomaly, []appliedBatch, error) {
+	t.Helper()
+
+	consumer := newFakeConsumer()
+	producerBugBatches(consumer)
+
+	var anomalies []StreamAnomaly
+	var applied []appliedBatch
+	worker := &Worker{
+		Source:    consumer,
+		Start:     es.Cursor("start"),
+		Validator: validator,
+		IdleSleep: time.Millisecond,
+		Hooks: Hooks{OnStreamAnomaly: func(a StreamAnomaly) {
+			anomalies = append(anomalies, a)
+		}},
+		Apply: func(ctx context.Context, batch []es.Envelope, next es.Cursor) error {
+			applied = append(applied, appliedBatch{batch: batch, cursor: next})
+			return nil
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	return anomalies, applied, worker.Run(ctx)
+}
+
+func TestStreamValidatorPolicyLog(t *testing.T) {
+	anomalies, applied, err := runValidator(t, &StreamValidator{})
+	if err != context.DeadlineExceeded {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+
+	want := []string{
+		`stream "a": version gap: expected 2, got 3 (event "a3")`,
+		`stream "b": version duplicate: expected 2, got 1 (event "b1-reused")`,
+		`stream "a": version regression: expected 4, got 2 (event "a2-late")`,
+	}
+	if len(anomalies) != len(want) {
+		t.Fatalf("expected %d anomalies, got %d: %v", len(want), len(anomalies), anomalies)
+	}
+	for i := range want {
+		if got := anomalies[i].Error(); got != want[i] {
+			t.Errorf("anomaly %d: expected %s, got %s", i, want[i], got)
+		}
+	}
+
+	// PolicyLog applies everything
+	if n := len(applied[0].batch) + len(applied[1].batch); n != 6 {
+		t.Errorf("expected all 6 events applied, got %d", n)
+	}
+}
+
+func TestStreamValidatorPolicySkip(t *testing.T) {
+	_, applied, _ := runValidator(t, &StreamValidator{Policy: PolicySkip})
+
+	if len(applied) != 2 {
+		t.Fatalf("expected 2 apply calls, got %d", len(applied))
+	}
+	if got := fmt.Sprint(eventIDsOf(applied[0].batch)); got != "[a1 b1 a3]" {
+		t.Errorf("expected gapped event to be applied, got %s", got)
+	}
+	if got := fmt.Sprint(eventIDsOf(applied[1].batch)); got != "[b2]" {
+		t.Errorf("expected duplicate and regression to be skipped, got %s", got)
+	}
+}
+
+func TestStreamValidatorPolicyHalt(t *testing.T) {
+	anomalies, applied, err := runValidator(t, &StreamValidator{Policy: PolicyHalt})
+
+	var a StreamAnomaly
+	if !errors.As(err, &a) || a.Kind != AnomalyGap || a.StreamID != "a" {
+		t.Fatalf("expected gap anomaly error on stream a, got %v", err)
+	}
+	if len(anomalies) != 1 {
+		t.Errorf("expected 1 reported anomaly, got %d", len(anomalies))
+	}
+	if len(applied) != 0 {
+		t.Errorf("expected the offending batch not to be applied, got %d apply calls", len(applied))
+	}
+}
+
+func TestStreamValidatorUsesStoreAcrossRuns(t *testing.T) {
+	store := &MemoryVersionStore{}
+	_ = store.SaveVersions(context.Background(), map[string]int64{"a": 4})
+
+	var anomalies []StreamAnomaly
+	v := &StreamValidator{Store: store}
+	_, updates, err := v.check(context.Background(), []es.Envelope{streamEvent("a", 5, "a5")}, func(a StreamAnomaly) {
+		anomalies = append(anomalies, a)
+	})
+	if err != nil || len(anomalies) != 0 {
+		t.Fatalf("expected version 5 to continue stored version 4, got %v / %v", anomalies, err)
+	}
+	if updates["a"] != 5 {
+		t.Errorf("expected update to version 5, got %v", updates)
+	}
+
+	// Versions are only saved once the batch is applied
+	if got, _ := store.LastVersions(context.Background(), []string{"a"}); got["a"] != 4 {
+		t.Errorf("expected stored version to remain 4 before save, got %d", got["a"])
+	}
+}
+
+func TestStreamValidatorResumesMidStream(t *testing.T) {
+	consumer := newFakeConsumer()
+	consumer.AddBatch([]es.Envelope{
+		streamEvent("a", 3, "a3"), // resumed from a checkpoint: a1 and a2 were applied before
+		streamEvent("a", 4, "a4"),
+		streamEvent("b", 7, "b7"),
+	}, es.Cursor("cursor1"))
+	consumer.AddBatch([]es.Envelope{streamEvent("a", 6, "a6")}, es.Cursor("cursor2"))
+
+	var pending []map[string]int64
+	worker := &Worker{
+		Source:    consumer,
+		Start:     es.Cursor("start"),
+		Validator: &StreamValidator{Policy: PolicyHalt},
+		IdleSleep: time.Millisecond,
+		Apply: func(ctx context.Context, batch []es.Envelope, next es.Cursor) error {
+			v, _ := PendingVersions(ctx)
+			pending = append(pending, v)
+			return nil
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := worker.Run(ctx)
+
+	// The first versions seen are the baseline; the later gap is still detected
+	var a StreamAnomaly
+	if !errors.As(err, &a) || a.Kind != AnomalyGap || a.Expected != 5 || a.Got != 6 {
+		t.Fatalf("expected only the gap before a6, got %v", err)
+	}
+	if len(pending) != 1 || pending[0]["a"] != 4 || pending[0]["b"] != 7 {
+		t.Errorf("expected Apply to see pending versions a=4 b=7, got %v", pending)
+	}
+}
+
+// nilVersionStore knows no streams and says so with a nil map
+type nilVersionStore struct{}
+
+func (nilVersionStore) LastVersions(ctx context.Context, streams []string) (map[string]int64, error) {
+	return nil, nil
+}
+
+func (nilVersionStore) SaveVersions(ctx context.Context, versions map[string]int64) error {
+	return nil
+}
+
+func TestStreamValidatorToleratesNilVersions(t *testing.T) {
+	v := &StreamValidator{Store: nilVersionStore{}}
+	batch := []es.Envelope{streamEvent("a", 1, "a1"), streamEvent("a", 2, "a2")}
+
+	kept, updates, err := v.check(context.Background(), batch, func(StreamAnomaly) {})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(kept) != 2 || updates["a"] != 2 {
+		t.Errorf("expected both events kept and a=2 to save, got %v / %v", eventIDsOf(kept), updates)
+	}
+}